@@ -0,0 +1,123 @@
+// Package pocket Create at 2026-10-18 15:40
+package pocket
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Changeset 记录需更新的列及值，按设置的顺序生成；值为nil时更新为NULL。
+// 可通过SqlBuilder.Changes生成UPDATE语句，或通过Xorm、Gorm转换为对应orm的更新map
+type Changeset struct {
+	columns []string
+	values  map[string]interface{}
+}
+
+// NewChangeset 空的Changeset
+func NewChangeset() *Changeset {
+	return &Changeset{values: make(map[string]interface{})}
+}
+
+// ChangesetOf 由Model生成Changeset，model为结构体指针，记录带set的生效字段(规则同插入，见NewSqlBuilder)，
+// 与BuildUpdate相同，pk、version及created列不记录
+func ChangesetOf(model interface{}) (*Changeset, error) {
+	originType := reflect.TypeOf(model)
+	if nil == originType || originType.Kind() != reflect.Ptr || originType.Elem().Kind() != reflect.Struct {
+		return nil, &BuildError{Op: "changeset", Row: -1, Detail: fmt.Sprintf("%T", model), Err: ErrModelKind}
+	}
+	originValue := reflect.ValueOf(model).Elem()
+	c := NewChangeset()
+	for _, f := range getModelMeta(originType.Elem()).fields {
+		if !f.updatable() {
+			continue
+		}
+		if v, ok := f.present(originValue); ok {
+			c.Set(f.column, v.Interface())
+		}
+	}
+	return c, nil
+}
+
+// Diff 比较同一类型的两个结构体(指针)快照，记录值不同的列，值取after中的值，after中为nil的指针更新为NULL；
+// 与ChangesetOf相同，只比较带set的列，pk、version及created列不记录
+func Diff(before, after interface{}) (*Changeset, error) {
+	beforeValue := reflect.Indirect(reflect.ValueOf(before))
+	afterValue := reflect.Indirect(reflect.ValueOf(after))
+	if beforeValue.Kind() != reflect.Struct || afterValue.Kind() != reflect.Struct || beforeValue.Type() != afterValue.Type() {
+		return nil, &BuildError{Op: "diff", Row: -1, Detail: fmt.Sprintf("%T, %T", before, after), Err: ErrModelKind}
+	}
+	c := NewChangeset()
+	for _, f := range getModelMeta(afterValue.Type()).fields {
+		if !f.updatable() {
+			continue
+		}
+		old, now := f.value(beforeValue), f.value(afterValue)
+		switch {
+		case !now.IsValid():
+			if old.IsValid() && !(old.Kind() == reflect.Ptr && old.IsNil()) {
+				c.SetNull(f.column)
+			}
+		case !old.IsValid() || !reflect.DeepEqual(old.Interface(), now.Interface()):
+			if now.Kind() == reflect.Ptr && now.IsNil() {
+				c.SetNull(f.column)
+			} else {
+				c.Set(f.column, now.Interface())
+			}
+		}
+	}
+	return c, nil
+}
+
+// Set 设置列的值，重复设置时覆盖原值并保持原顺序
+func (c *Changeset) Set(column string, value interface{}) *Changeset {
+	if _, ok := c.values[column]; !ok {
+		c.columns = append(c.columns, column)
+	}
+	c.values[column] = value
+	return c
+}
+
+// SetNull 将列更新为NULL
+func (c *Changeset) SetNull(column string) *Changeset {
+	return c.Set(column, nil)
+}
+
+// Has 是否设置了列
+func (c *Changeset) Has(column string) bool {
+	_, ok := c.values[column]
+	return ok
+}
+
+// Get 列的值
+func (c *Changeset) Get(column string) (interface{}, bool) {
+	v, ok := c.values[column]
+	return v, ok
+}
+
+// Columns 设置的列，按设置的顺序
+func (c *Changeset) Columns() []string {
+	return append([]string(nil), c.columns...)
+}
+
+// Len 设置的列数
+func (c *Changeset) Len() int {
+	return len(c.columns)
+}
+
+// Xorm 转换为xorm的更新map，用于 session.Table(table).Where(...).Update(map)
+func (c *Changeset) Xorm() map[string]interface{} {
+	return c.toMap()
+}
+
+// Gorm 转换为gorm的更新map，用于 db.Model(model).Updates(map)，值为nil的列更新为NULL
+func (c *Changeset) Gorm() map[string]interface{} {
+	return c.toMap()
+}
+
+func (c *Changeset) toMap() map[string]interface{} {
+	params := make(map[string]interface{}, len(c.columns))
+	for _, column := range c.columns {
+		params[column] = c.values[column]
+	}
+	return params
+}
@@ -0,0 +1,324 @@
+// Package pocket Create at 2026-10-18 11:35
+package pocket
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Expr 条件表达式，用于SqlBuilder.Condition，比较的值为*SqlBuilder时作为子查询；也可由条件结构体的tag生成：
+// `db:"column,op=eq|ne|gt|gte|lt|lte|like|in|nin|between|null"`，默认eq。
+// 字段是否生效与插入时相同：指针及切片字段非nil时生效，值字段带omitempty时非零值生效、带zero时始终生效；null字段为*bool，true为IS NULL，false为IS NOT NULL
+type Expr interface {
+	build(d Dialect) (string, []interface{}, error)
+}
+
+// compareExpr 比较条件
+type compareExpr struct {
+	column string
+	op     string
+	value  interface{}
+}
+
+func (e compareExpr) build(d Dialect) (string, []interface{}, error) {
+	column, err := d.quoteIdent(e.column)
+	if nil != err {
+		return "", nil, err
+	}
+	if isNull(e.value) {
+		// = NULL 恒不成立，Eq/Ne转换为IS NULL/IS NOT NULL，其他操作符无意义
+		switch e.op {
+		case "=":
+			return column + " IS NULL", []interface{}{}, nil
+		case "<>":
+			return column + " IS NOT NULL", []interface{}{}, nil
+		default:
+			return "", nil, &BuildError{Op: "where", Field: e.column, Row: -1, Detail: strings.TrimSpace(e.op) + " NULL", Err: ErrCondition}
+		}
+	}
+	return operand(d, column+e.op, e.value)
+}
+
+// isNull 值是否为nil或nil指针，Sensitive取其原值判断
+func isNull(v interface{}) bool {
+	if s, ok := v.(Sensitive); ok {
+		v = s.V
+	}
+	if nil == v {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+// inExpr IN条件，切片展开为对应个数的占位符
+type inExpr struct {
+	column string
+	values interface{}
+	not    bool
+}
+
+func (e inExpr) build(d Dialect) (string, []interface{}, error) {
+	column, err := d.quoteIdent(e.column)
+	if nil != err {
+		return "", nil, err
+	}
+	if _, ok := e.values.(*SqlBuilder); ok {
+		if e.not {
+			return operand(d, column+" NOT IN ", e.values)
+		}
+		return operand(d, column+" IN ", e.values)
+	}
+	// 敏感的值列表，逐个包装
+	values, sensitive := e.values.(Sensitive)
+	if sensitive {
+		e.values = values.V
+	}
+	v := reflect.ValueOf(e.values)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return "", nil, &BuildError{Op: "where", Field: e.column, Row: -1, Detail: fmt.Sprintf("in %T", e.values), Err: ErrCondition}
+	}
+	if v.Len() == 0 {
+		// 空列表，IN恒为假，NOT IN恒为真
+		if e.not {
+			return "1=1", []interface{}{}, nil
+		}
+		return "1=0", []interface{}{}, nil
+	}
+	params := make([]interface{}, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		if sensitive {
+			params = append(params, Sensitive{V: v.Index(i).Interface()})
+		} else {
+			params = append(params, v.Index(i).Interface())
+		}
+	}
+	op := " IN ("
+	if e.not {
+		op = " NOT IN ("
+	}
+	return column + op + strings.TrimSuffix(strings.Repeat("?,", v.Len()), ",") + ")", params, nil
+}
+
+// betweenExpr BETWEEN条件
+type betweenExpr struct {
+	column string
+	from   interface{}
+	to     interface{}
+}
+
+func (e betweenExpr) build(d Dialect) (string, []interface{}, error) {
+	column, err := d.quoteIdent(e.column)
+	if nil != err {
+		return "", nil, err
+	}
+	return column + " BETWEEN ? AND ?", []interface{}{e.from, e.to}, nil
+}
+
+// nullExpr IS NULL条件
+type nullExpr struct {
+	column string
+	not    bool
+}
+
+func (e nullExpr) build(d Dialect) (string, []interface{}, error) {
+	column, err := d.quoteIdent(e.column)
+	if nil != err {
+		return "", nil, err
+	}
+	if e.not {
+		return column + " IS NOT NULL", []interface{}{}, nil
+	}
+	return column + " IS NULL", []interface{}{}, nil
+}
+
+// groupExpr AND/OR组合条件
+type groupExpr struct {
+	op    string
+	exprs []Expr
+}
+
+func (e groupExpr) build(d Dialect) (string, []interface{}, error) {
+	list := make([]string, 0, len(e.exprs))
+	params := make([]interface{}, 0)
+	for _, expr := range e.exprs {
+		if nil == expr {
+			continue
+		}
+		sql, param, err := expr.build(d)
+		if nil != err {
+			return "", nil, err
+		}
+		if "" == sql {
+			continue
+		}
+		if g, ok := expr.(groupExpr); ok && g.op == " AND " && e.op == " OR " && len(g.exprs) > 1 {
+			sql = "(" + sql + ")"
+		}
+		list = append(list, sql)
+		params = append(params, param...)
+	}
+	if len(list) == 0 {
+		return "", params, nil
+	}
+	if e.op == " OR " && len(list) > 1 {
+		// OR组合始终加括号，避免与外层AND的优先级问题
+		return "(" + strings.Join(list, e.op) + ")", params, nil
+	}
+	return strings.Join(list, e.op), params, nil
+}
+
+// notExpr NOT条件
+type notExpr struct {
+	expr Expr
+}
+
+func (e notExpr) build(d Dialect) (string, []interface{}, error) {
+	sql, params, err := e.expr.build(d)
+	if nil != err || "" == sql {
+		return sql, params, err
+	}
+	return "NOT (" + sql + ")", params, nil
+}
+
+// Eq column = value，value为nil时为 column IS NULL
+func Eq(column string, value interface{}) Expr {
+	return compareExpr{column: column, op: "=", value: value}
+}
+
+// Ne column <> value，value为nil时为 column IS NOT NULL
+func Ne(column string, value interface{}) Expr {
+	return compareExpr{column: column, op: "<>", value: value}
+}
+
+// Gt column > value，value为nil时返回ErrCondition，Gte、Lt、Lte、Like相同
+func Gt(column string, value interface{}) Expr {
+	return compareExpr{column: column, op: ">", value: value}
+}
+
+// Gte column >= value
+func Gte(column string, value interface{}) Expr {
+	return compareExpr{column: column, op: ">=", value: value}
+}
+
+// Lt column < value
+func Lt(column string, value interface{}) Expr {
+	return compareExpr{column: column, op: "<", value: value}
+}
+
+// Lte column <= value
+func Lte(column string, value interface{}) Expr {
+	return compareExpr{column: column, op: "<=", value: value}
+}
+
+// Like column LIKE pattern，通配符由调用方传入
+func Like(column string, pattern interface{}) Expr {
+	return compareExpr{column: column, op: " LIKE ", value: pattern}
+}
+
+// In column IN (...)，values为切片、数组或*SqlBuilder子查询
+func In(column string, values interface{}) Expr {
+	return inExpr{column: column, values: values}
+}
+
+// NotIn column NOT IN (...)，values为切片、数组或*SqlBuilder子查询
+func NotIn(column string, values interface{}) Expr {
+	return inExpr{column: column, values: values, not: true}
+}
+
+// Between column BETWEEN from AND to
+func Between(column string, from, to interface{}) Expr {
+	return betweenExpr{column: column, from: from, to: to}
+}
+
+// IsNull column IS NULL
+func IsNull(column string) Expr {
+	return nullExpr{column: column}
+}
+
+// NotNull column IS NOT NULL
+func NotNull(column string) Expr {
+	return nullExpr{column: column, not: true}
+}
+
+// And 条件与，nil及空条件忽略
+func And(exprs ...Expr) Expr {
+	return groupExpr{op: " AND ", exprs: exprs}
+}
+
+// Or 条件或，nil及空条件忽略
+func Or(exprs ...Expr) Expr {
+	return groupExpr{op: " OR ", exprs: exprs}
+}
+
+// Not 条件非
+func Not(expr Expr) Expr {
+	return notExpr{expr: expr}
+}
+
+// condExpr 将Condition的参数转换为Expr，结构体指针按tag生成AND条件，alias非空时以其限定列名
+func condExpr(cond interface{}, alias string) (Expr, error) {
+	if expr, ok := cond.(Expr); ok {
+		return expr, nil
+	}
+	originType := reflect.TypeOf(cond)
+	if nil == originType || originType.Kind() != reflect.Ptr || originType.Elem().Kind() != reflect.Struct {
+		return nil, &BuildError{Op: "where", Row: -1, Detail: fmt.Sprintf("%T", cond), Err: ErrCondition}
+	}
+	meta := getModelMeta(originType.Elem())
+	if len(meta.invalid) > 0 {
+		return nil, buildErr("where", ErrInvalidIdentifier, meta.invalid[0])
+	}
+	originValue := reflect.ValueOf(cond).Elem()
+	exprs := make([]Expr, 0)
+	for _, f := range meta.fields {
+		field, ok := f.present(originValue)
+		if !ok {
+			continue
+		}
+		column := f.column
+		if "" != alias {
+			column = alias + "." + column
+		}
+		op, _ := f.option("op")
+		switch op {
+		case "", "eq":
+			exprs = append(exprs, Eq(column, f.param(field.Interface())))
+		case "ne":
+			exprs = append(exprs, Ne(column, f.param(field.Interface())))
+		case "gt":
+			exprs = append(exprs, Gt(column, f.param(field.Interface())))
+		case "gte":
+			exprs = append(exprs, Gte(column, f.param(field.Interface())))
+		case "lt":
+			exprs = append(exprs, Lt(column, f.param(field.Interface())))
+		case "lte":
+			exprs = append(exprs, Lte(column, f.param(field.Interface())))
+		case "like":
+			exprs = append(exprs, Like(column, f.param(field.Interface())))
+		case "in":
+			exprs = append(exprs, In(column, f.param(reflect.Indirect(field).Interface())))
+		case "nin":
+			exprs = append(exprs, NotIn(column, f.param(reflect.Indirect(field).Interface())))
+		case "between":
+			v := reflect.Indirect(field)
+			if (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || v.Len() != 2 {
+				return nil, &BuildError{Op: "where", Field: column, Row: -1, Detail: "between needs 2 values", Err: ErrCondition}
+			}
+			exprs = append(exprs, Between(column, f.param(v.Index(0).Interface()), f.param(v.Index(1).Interface())))
+		case "null":
+			if field.Kind() != reflect.Ptr || field.Elem().Kind() != reflect.Bool {
+				return nil, &BuildError{Op: "where", Field: column, Row: -1, Detail: "null needs *bool", Err: ErrCondition}
+			}
+			if field.Elem().Bool() {
+				exprs = append(exprs, IsNull(column))
+			} else {
+				exprs = append(exprs, NotNull(column))
+			}
+		default:
+			return nil, &BuildError{Op: "where", Field: column, Row: -1, Detail: "op=" + op, Err: ErrCondition}
+		}
+	}
+	return And(exprs...), nil
+}
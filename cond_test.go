@@ -0,0 +1,40 @@
+package pocket
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCompareNull(t *testing.T) {
+	var nilName *string
+	cases := []struct {
+		expr   Expr
+		sql    string
+		params []interface{}
+	}{
+		{Eq("n", nil), "`n` IS NULL", []interface{}{}},
+		{Eq("n", nilName), "`n` IS NULL", []interface{}{}},
+		{Ne("n", nil), "`n` IS NOT NULL", []interface{}{}},
+		{And(Eq("a", 1), Eq("n", Sensitive{})), "`a`=? AND `n` IS NULL", []interface{}{1}},
+		{Eq("n", 0), "`n`=?", []interface{}{0}},
+	}
+	for _, c := range cases {
+		sql, params, err := c.expr.build(MySQL)
+		if nil != err || sql != c.sql || !reflect.DeepEqual(params, c.params) {
+			t.Errorf("build = %s, %v, %v, want %s, %v", sql, params, err, c.sql, c.params)
+		}
+	}
+	for _, expr := range []Expr{Gt("n", nil), Gte("n", nil), Lt("n", nilName), Lte("n", nil), Like("n", nil)} {
+		if _, _, err := expr.build(MySQL); !errors.Is(err, ErrCondition) {
+			t.Errorf("%#v err = %v, want ErrCondition", expr, err)
+		}
+	}
+}
+
+func TestInExpand(t *testing.T) {
+	sql, params, err := And(In("id", []int{1, 2, 3}), Gt("age", 18), NotIn("s", []string{})).build(PostgreSQL)
+	if nil != err || sql != `"id" IN (?,?,?) AND "age">? AND 1=1` || !reflect.DeepEqual(params, []interface{}{1, 2, 3, 18}) {
+		t.Errorf("build = %s, %v, %v", sql, params, err)
+	}
+}
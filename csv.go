@@ -0,0 +1,240 @@
+// Package pocket Create at 2026-10-18 18:20
+package pocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/transform"
+)
+
+// CsvEncoding csv文件编码
+type CsvEncoding int
+
+const (
+	// CsvUTF8 UTF-8，导入时自动去除BOM
+	CsvUTF8 CsvEncoding = iota
+	// CsvUTF8BOM UTF-8，导出时写入BOM，使Excel按UTF-8打开
+	CsvUTF8BOM
+	// CsvGBK GBK，兼容中文Windows下的Excel；导出包含GBK无法表示的字符时返回错误
+	CsvGBK
+)
+
+// utf8BOM UTF-8的BOM
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// CsvOption csv格式，零值为逗号分隔、UTF-8、仅在需要时加引号
+type CsvOption struct {
+	Comma      rune        // 分隔符，为0时使用逗号
+	Encoding   CsvEncoding // 编码
+	QuoteAll   bool        // 导出时所有字段加引号，否则仅在包含分隔符、引号、换行或首尾空格时加引号
+	LazyQuotes bool        // 导入时允许不规范的引号，如未加引号的字段中出现引号
+}
+
+// TSV 以制表符分隔的格式
+var TSV = CsvOption{Comma: '\t'}
+
+// comma 分隔符
+func (o CsvOption) comma() rune {
+	if 0 == o.Comma {
+		return ','
+	}
+	return o.Comma
+}
+
+// ExportCsv 导出csv到w，列、表头及formatter与Export相同，按excel_column的列号排列，空闲的列为空；
+// 逐行读取Sheet.Source(或Content)，样式、列宽、合并等只对Excel有效的设置忽略
+func ExportCsv(w io.Writer, s Sheet, option CsvOption) (err error) {
+	source, first, list, err := exportSource(s)
+	if nil != err {
+		return err
+	}
+	comma := option.comma()
+	if '"' == comma || '\r' == comma || '\n' == comma || utf8.RuneError == comma {
+		DefaultLogger.Error("非法的分隔符")
+		return fmt.Errorf("非法的分隔符 %q", comma)
+	}
+	switch option.Encoding {
+	case CsvUTF8BOM:
+		if _, err = w.Write(utf8BOM); nil != err {
+			DefaultLogger.Error(err.Error())
+			return err
+		}
+	case CsvGBK:
+		tw := transform.NewWriter(w, simplifiedchinese.GBK.NewEncoder())
+		defer func() {
+			if e := tw.Close(); nil == err && nil != e {
+				DefaultLogger.Error(e.Error())
+				err = e
+			}
+		}()
+		w = tw
+	}
+	cw := newCsvWriter(w, option)
+
+	width := 0
+	formatter := make(map[string]Format, 0)
+	for _, ec := range list {
+		if f := ec.format.exportFormatter(); nil != f {
+			formatter[ec.title] = f
+		}
+		if ec.number > width {
+			width = ec.number
+		}
+	}
+	record := make([]string, width)
+	for _, ec := range list {
+		record[ec.number-1] = ec.title
+	}
+	if err = cw.write(record); nil != err {
+		DefaultLogger.Error(err.Error())
+		return err
+	}
+	for index := 0; ; index++ {
+		r := first
+		if index > 0 || nil == first {
+			if !source.Next() {
+				break
+			}
+			r = source.Value()
+		}
+		row := reflect.Indirect(reflect.ValueOf(r))
+		record := make([]string, width)
+		for _, ec := range list {
+			val := cellValue(row.Field(ec.field))
+			if f, ok := formatter[ec.title]; ok {
+				val = f.format(val)
+			}
+			record[ec.number-1] = csvValue(val)
+		}
+		if err = cw.write(record); nil != err {
+			DefaultLogger.Error(err.Error())
+			return err
+		}
+	}
+	if err = source.Err(); nil != err {
+		DefaultLogger.Error(err.Error())
+		return err
+	}
+	if err = cw.flush(); nil != err {
+		DefaultLogger.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// ImportCsv 导入csv到s.Result，只使用s.Name(报告中的sheet名称)、s.T及s.Result；
+// 第一行为表头，按excel_column的标题匹配，校验及错误报告同Import，报告中的行号为记录序号(表头为第1行)
+func ImportCsv(reader io.Reader, s Sheet, option CsvOption) error {
+	list := make([]interface{}, 0)
+	err := importCsv(reader, s.Name, s.T, option, func(row int, bean interface{}) error {
+		list = append(list, bean)
+		return nil
+	})
+	if _, ok := err.(*ImportReport); nil != err && !ok {
+		return err
+	}
+	if nil != s.Result {
+		*(s.Result) = list
+	}
+	return err
+}
+
+// ImportCsvEach 逐行导入csv，回调及错误的处理同ImportEach
+func ImportCsvEach(reader io.Reader, t reflect.Type, option CsvOption, fn func(row int, bean interface{}) error) error {
+	return importCsv(reader, "", t, option, fn)
+}
+
+// importCsv 按编码读取csv后交由importRows解码
+func importCsv(reader io.Reader, name string, t reflect.Type, option CsvOption, fn func(row int, bean interface{}) error) error {
+	if nil == t {
+		DefaultLogger.Error("无法识别类型")
+		return errors.New("无法识别类型")
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if CsvGBK == option.Encoding {
+		reader = transform.NewReader(reader, simplifiedchinese.GBK.NewDecoder())
+	} else {
+		br := bufio.NewReader(reader)
+		if head, err := br.Peek(len(utf8BOM)); nil == err && bytes.Equal(head, utf8BOM) {
+			br.Discard(len(utf8BOM))
+		}
+		reader = br
+	}
+	cr := csv.NewReader(reader)
+	cr.Comma = option.comma()
+	cr.LazyQuotes = option.LazyQuotes
+	// 各行的列数可不同，缺少的列按空处理
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	report := &ImportReport{}
+	err := importRows(name, t, cr.Read, report, fn)
+	if nil != err && !errors.Is(err, ErrStopImport) {
+		return err
+	}
+	if len(report.Issues) > 0 {
+		return report
+	}
+	return nil
+}
+
+// csvValue 导出的字段值，时间格式化为 2006-01-02 15:04:05
+func csvValue(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case time.Time:
+		return val.Format("2006-01-02 15:04:05")
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// csvWriter 按CsvOption写入记录，QuoteAll时自行加引号，否则使用csv.Writer
+type csvWriter struct {
+	w     *bufio.Writer
+	cw    *csv.Writer
+	comma string
+}
+
+func newCsvWriter(w io.Writer, option CsvOption) *csvWriter {
+	if !option.QuoteAll {
+		cw := csv.NewWriter(w)
+		cw.Comma = option.comma()
+		return &csvWriter{cw: cw}
+	}
+	return &csvWriter{w: bufio.NewWriter(w), comma: string(option.comma())}
+}
+
+func (c *csvWriter) write(record []string) error {
+	if nil != c.cw {
+		return c.cw.Write(record)
+	}
+	for i, field := range record {
+		if i > 0 {
+			c.w.WriteString(c.comma)
+		}
+		c.w.WriteString(`"` + strings.Replace(field, `"`, `""`, -1) + `"`)
+	}
+	_, err := c.w.WriteString("\n")
+	return err
+}
+
+func (c *csvWriter) flush() error {
+	if nil != c.cw {
+		c.cw.Flush()
+		return c.cw.Error()
+	}
+	return c.w.Flush()
+}
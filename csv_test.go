@@ -0,0 +1,87 @@
+package pocket
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type csvUser struct {
+	Name string `excel_column:"姓名"`
+	Note string `excel_column:"备注"`
+	Age  int    `excel_column:"年龄,index=4"`
+}
+
+var csvUsers = []interface{}{
+	&csvUser{Name: "张三", Note: "a,b", Age: 18},
+	&csvUser{Name: "李四", Note: `说"好"`, Age: 20},
+	&csvUser{Name: "王五", Note: " 前后空格 ", Age: 30},
+}
+
+// csvRoundTrip 以option导出csvUsers后再导入，返回导出的内容及导入的结果
+func csvRoundTrip(t *testing.T, option CsvOption) ([]byte, []interface{}) {
+	var buf bytes.Buffer
+	if err := ExportCsv(&buf, Sheet{Content: csvUsers}, option); nil != err {
+		t.Fatal(err)
+	}
+	result := make([]interface{}, 0)
+	err := ImportCsv(bytes.NewReader(buf.Bytes()), Sheet{Name: "csv", T: reflect.TypeOf(csvUser{}), Result: &result}, option)
+	if nil != err {
+		t.Fatal(err)
+	}
+	return buf.Bytes(), result
+}
+
+func TestCsvRoundTrip(t *testing.T) {
+	cases := []struct {
+		name   string
+		option CsvOption
+		header string
+	}{
+		{"utf8", CsvOption{}, "姓名,备注,,年龄\n"},
+		{"bom", CsvOption{Encoding: CsvUTF8BOM}, "\xEF\xBB\xBF姓名,备注,,年龄\n"},
+		{"quote all", CsvOption{QuoteAll: true}, `"姓名","备注","","年龄"` + "\n"},
+		{"tsv", TSV, "姓名\t备注\t\t年龄\n"},
+		{"gbk", CsvOption{Encoding: CsvGBK}, "\xd0\xd5\xc3\xfb,\xb1\xb8\xd7\xa2,,\xc4\xea\xc1\xe4\n"},
+	}
+	for _, c := range cases {
+		data, result := csvRoundTrip(t, c.option)
+		if !bytes.HasPrefix(data, []byte(c.header)) {
+			t.Errorf("%s header = %q, want %q", c.name, data[:len(c.header)], c.header)
+		}
+		if len(result) != len(csvUsers) {
+			t.Errorf("%s imported %d rows, want %d", c.name, len(result), len(csvUsers))
+			continue
+		}
+		for i := range result {
+			if !reflect.DeepEqual(result[i], csvUsers[i]) {
+				t.Errorf("%s row %d = %+v, want %+v", c.name, i, result[i], csvUsers[i])
+			}
+		}
+	}
+
+	data, _ := csvRoundTrip(t, CsvOption{QuoteAll: true})
+	if line := strings.Split(string(data), "\n")[2]; line != `"李四","说""好""","","20"` {
+		t.Errorf("quote all row = %s", line)
+	}
+	data, _ = csvRoundTrip(t, CsvOption{})
+	if line := strings.Split(string(data), "\n")[1]; line != `张三,"a,b",,18` {
+		t.Errorf("minimal quoting row = %s", line)
+	}
+
+	// 未指定BOM时导入也去除BOM
+	result := make([]interface{}, 0)
+	err := ImportCsv(strings.NewReader("\xEF\xBB\xBF姓名,年龄\n赵六,1\n"), Sheet{T: reflect.TypeOf(csvUser{}), Result: &result}, CsvOption{})
+	if nil != err || len(result) != 1 || result[0].(*csvUser).Name != "赵六" {
+		t.Errorf("import with BOM = %v, %v", result, err)
+	}
+}
+
+func TestCsvGBKUnsupported(t *testing.T) {
+	var buf bytes.Buffer
+	err := ExportCsv(&buf, Sheet{Content: []interface{}{&csvUser{Name: "emoji 😀", Age: 1}}}, CsvOption{Encoding: CsvGBK})
+	if nil == err {
+		t.Errorf("export of a character outside GBK should fail")
+	}
+}
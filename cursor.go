@@ -0,0 +1,147 @@
+// Package pocket Create at 2026-10-18 15:05
+package pocket
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+)
+
+// RowSource 逐行读取的数据来源，Next返回false后由Err返回读取过程中的错误；Cursor实现了该接口，可用作Sheet.Source
+type RowSource interface {
+	Next() bool
+	Value() interface{}
+	Err() error
+}
+
+// Cursor 流式读取查询结果，每次Next将当前行按db tag解码为一个新的结构体，不会一次加载全部结果
+type Cursor struct {
+	ctx     context.Context
+	rows    *sql.Rows
+	t       reflect.Type
+	isPtr   bool
+	columns []string
+	value   interface{}
+	err     error
+}
+
+// NewCursor model为结构体或结构体指针，决定每行解码的类型，Value返回与model相同形式的值；
+// ctx取消后Next返回false，Err返回ctx.Err()
+func NewCursor(ctx context.Context, rows *sql.Rows, model interface{}) (*Cursor, error) {
+	t := reflect.TypeOf(model)
+	isPtr := nil != t && t.Kind() == reflect.Ptr
+	if isPtr {
+		t = t.Elem()
+	}
+	if nil == t || t.Kind() != reflect.Struct {
+		rows.Close()
+		return nil, &BuildError{Op: "cursor", Row: -1, Detail: fmt.Sprintf("%T", model), Err: ErrModelKind}
+	}
+	columns, err := rows.Columns()
+	if nil != err {
+		rows.Close()
+		return nil, err
+	}
+	return &Cursor{ctx: ctx, rows: rows, t: t, isPtr: isPtr, columns: columns}, nil
+}
+
+// Next 读取下一行，无更多数据、出错或ctx取消时返回false并关闭rows
+func (c *Cursor) Next() bool {
+	if nil != c.err {
+		return false
+	}
+	if err := c.ctx.Err(); nil != err {
+		c.err = err
+		c.rows.Close()
+		return false
+	}
+	if !c.rows.Next() {
+		c.err = c.rows.Err()
+		c.rows.Close()
+		return false
+	}
+	item := reflect.New(c.t)
+	if err := scanStruct(c.rows, c.columns, item.Elem()); nil != err {
+		c.err = err
+		c.rows.Close()
+		return false
+	}
+	if c.isPtr {
+		c.value = item.Interface()
+	} else {
+		c.value = item.Elem().Interface()
+	}
+	return true
+}
+
+// Value 当前行
+func (c *Cursor) Value() interface{} {
+	return c.value
+}
+
+// Err 读取过程中的错误
+func (c *Cursor) Err() error {
+	return c.err
+}
+
+// Close 提前结束读取时关闭rows
+func (c *Cursor) Close() error {
+	return c.rows.Close()
+}
+
+// sliceSource 以切片作为RowSource
+type sliceSource struct {
+	rows  []interface{}
+	index int
+}
+
+func (s *sliceSource) Next() bool {
+	if s.index >= len(s.rows) {
+		return false
+	}
+	s.index++
+	return true
+}
+
+func (s *sliceSource) Value() interface{} {
+	return s.rows[s.index-1]
+}
+
+func (s *sliceSource) Err() error {
+	return nil
+}
+
+// ChanSource 以channel作为RowSource，读取到channel关闭或ctx取消为止，ctx取消时Err返回ctx.Err()
+func ChanSource(ctx context.Context, ch <-chan interface{}) RowSource {
+	return &chanSource{ctx: ctx, ch: ch}
+}
+
+type chanSource struct {
+	ctx   context.Context
+	ch    <-chan interface{}
+	value interface{}
+	err   error
+}
+
+func (s *chanSource) Next() bool {
+	if nil != s.err {
+		return false
+	}
+	select {
+	case <-s.ctx.Done():
+		s.err = s.ctx.Err()
+		return false
+	case v, ok := <-s.ch:
+		s.value = v
+		return ok
+	}
+}
+
+func (s *chanSource) Value() interface{} {
+	return s.value
+}
+
+func (s *chanSource) Err() error {
+	return s.err
+}
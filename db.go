@@ -4,28 +4,104 @@ package pocket
 import (
 	"errors"
 	"fmt"
-	"log"
 	"reflect"
 	"strings"
+	"time"
 )
 
+// DefaultBatchRows 分批插入时每条语句的默认最大行数
+const DefaultBatchRows = 1000
+
+type SqlBuilder struct {
+	Table     string
+	Model     interface{}
+	Cond      interface{}
+	dialect   Dialect
+	returning []string
+	fullTable bool
+	unscoped  bool
+	limit     int
+	offset    int
+	after     []interface{}
+	alias     string
+	joins     []join
+	columns   []interface{}
+	groupBy   []string
+	having    Expr
+	orderBy   []sortColumn
+	pick      []string
+	sparse    Sparse
+	changes   *Changeset
+	conflict  []string
+	err       error
+}
+
+// Sparse 批量插入时各行的列不一致的处理方式
+type Sparse uint8
+
 const (
-	generateSqlErr = "生成sql语句错误"
-	columnErr      = "列数不一致"
+	// SparseStrict 各行的列需一致，否则返回ErrInconsistentColumns
+	SparseStrict Sparse = iota
+	// SparseDefault 取所有行的列的并集，缺少的值填DEFAULT，方言不支持时返回ErrDefaultValue，可用BuildInsertBatch按列分组
+	SparseDefault
+	// SparseNull 取所有行的列的并集，缺少的值填NULL
+	SparseNull
+	// SparseGroup 按列分组，BuildInsertBatch每组生成独立的语句，BuildInsert同SparseStrict；
+	// 组按首行的顺序排列，组内保持原顺序，因此插入顺序会改变，如[a],[a,b],[c],[a]中第4行先于第2行插入
+	SparseGroup
 )
 
-type SqlBuilder struct {
-	Table string
-	Model interface{}
-	Cond  interface{}
+// insertColumn 插入行中的列及值
+type insertColumn struct {
+	column string
+	value  interface{}
 }
 
-// NewSqlBuilder sql builder tag规则 `db:"column,add,set,sort"`
-func NewSqlBuilder(table string, model interface{}) *SqlBuilder {
-	return &SqlBuilder{Table: table, Model: model}
+// Statement sql语句及参数
+type Statement struct {
+	Sql    string
+	Params []interface{}
 }
 
-// BuildInsertRow 生成单条插入sql
+// sortColumn 排序列
+type sortColumn struct {
+	column string
+	desc   bool
+}
+
+// NewSqlBuilder sql builder tag规则 `db:"column,add,set,sort,deleted,pk,unique,omitempty,zero"`，sort默认升序，`sort=desc`为降序，
+// deleted为软删除标记列，pk/unique为upsert的冲突键，unique=name同名的列组成联合唯一键；指针及切片字段非nil时写入，
+// 值字段带omitempty时非零值写入，带zero时始终写入，都不带时忽略，零值的pk/auto列插入时不写入；
+// 未打tag的匿名嵌入结构体(指针)字段展开；created/updated列插入时为空则填充当前时间，updated列更新时设为当前时间，
+// version列插入时为空则填充1，更新时自增并以Model中的值作为乐观锁条件；sensitive列的参数包装为Sensitive，日志中不显示；
+// dialect为sql方言，默认MySQL
+func NewSqlBuilder(table string, model interface{}, dialect ...Dialect) *SqlBuilder {
+	builder := &SqlBuilder{Table: table, Model: model}
+	if len(dialect) > 0 {
+		builder.dialect = dialect[0]
+	}
+	return builder
+}
+
+// Dialect sql方言
+func (builder *SqlBuilder) Dialect() Dialect {
+	return builder.dialect
+}
+
+// Sparse 设置批量插入时各行的列不一致的处理方式，默认SparseStrict
+func (builder *SqlBuilder) Sparse(mode Sparse) *SqlBuilder {
+	builder.sparse = mode
+	return builder
+}
+
+// Changes 设置更新的列，BuildUpdate以其代替Model中带set的字段；Model仍用于version、updated及列名校验，可为nil，
+// Changeset中version列的值被忽略，version列始终自增
+func (builder *SqlBuilder) Changes(c *Changeset) *SqlBuilder {
+	builder.changes = c
+	return builder
+}
+
+// Condition 设置条件，c为Expr或条件结构体指针，结构体中非nil的字段按tag生成WHERE条件，见Expr
 func (builder *SqlBuilder) Condition(c interface{}) *SqlBuilder {
 	builder.Cond = c
 	return builder
@@ -33,201 +109,963 @@ func (builder *SqlBuilder) Condition(c interface{}) *SqlBuilder {
 
 // BuildInsertRow 生成单条插入sql
 func (builder *SqlBuilder) BuildInsertRow() (string, []interface{}, error) {
-	sql, param := builder.generate("add-row")
-	if "" == sql {
-		DefaultLogger.Error(generateSqlErr)
-		return "", nil, errors.New(generateSqlErr)
+	if err := builder.validate(); nil != err {
+		return "", nil, withOp(err, "insert")
+	}
+	sql, param, err := builder.generate("add-row")
+	if nil != err {
+		return "", nil, err
+	}
+	returning, err := builder.generateReturning()
+	if nil != err {
+		return "", nil, err
+	}
+
+	return builder.dialect.Rebind("INSERT INTO " + builder.dialect.Quote(builder.Table) + sql + returning), param, nil
+}
+
+// AllowFullTable 允许生成不带WHERE条件的语句
+func (builder *SqlBuilder) AllowFullTable() *SqlBuilder {
+	builder.fullTable = true
+	return builder
+}
+
+// Unscoped 忽略软删除标记，删除时物理删除，查询时包含已软删除的记录
+func (builder *SqlBuilder) Unscoped() *SqlBuilder {
+	builder.unscoped = true
+	return builder
+}
+
+// Returning 设置INSERT/UPDATE/DELETE返回的列，仅支持RETURNING的方言可用
+func (builder *SqlBuilder) Returning(columns ...string) *SqlBuilder {
+	builder.returning = columns
+	return builder
+}
+
+// Limit 设置分页，limit<=0时不分页
+func (builder *SqlBuilder) Limit(limit, offset int) *SqlBuilder {
+	builder.limit = limit
+	builder.offset = offset
+	return builder
+}
+
+// After 游标分页，values为上一页最后一条记录的排序列的值，顺序与sort字段一致
+func (builder *SqlBuilder) After(values ...interface{}) *SqlBuilder {
+	builder.after = values
+	return builder
+}
+
+// BuildUpdate 生成更新sql，Model中tag带set的非nil字段为更新列，Cond中非nil字段为条件
+func (builder *SqlBuilder) BuildUpdate() (string, []interface{}, error) {
+	if err := builder.validate(); nil != err {
+		return "", nil, withOp(err, "update")
+	}
+	set, param, err := builder.generate("set")
+	if nil != err {
+		return "", nil, err
+	}
+	where, whereParam, err := builder.generate("where")
+	if nil != err {
+		return "", nil, withOp(err, "update")
+	}
+	if "" == where && !builder.fullTable {
+		return "", nil, buildErr("update", ErrNoCondition, "")
+	}
+	if version, versionParam, _ := builder.generate("version"); "" != version {
+		if "" != where {
+			where += " AND "
+		}
+		where += version
+		whereParam = append(whereParam, versionParam...)
+	}
+	returning, err := builder.generateReturning()
+	if nil != err {
+		return "", nil, err
 	}
 
-	return "INSERT INTO `" + builder.Table + "`" + sql, param, nil
+	sql := "UPDATE " + builder.dialect.Quote(builder.Table) + " SET " + set
+	if "" != where {
+		sql += " WHERE " + where
+	}
+	return builder.dialect.Rebind(sql + returning), append(param, whereParam...), nil
 }
 
-// BuildInsert 生成批量插入sql
+// BuildInsert 生成批量插入sql，各行的列不一致时的处理见Sparse
 func (builder *SqlBuilder) BuildInsert() (string, []interface{}, error) {
-	sql, param := builder.generate("add-rows")
-	if "" == sql {
-		DefaultLogger.Error(generateSqlErr)
-		return "", nil, errors.New(generateSqlErr)
+	if err := builder.validate(); nil != err {
+		return "", nil, withOp(err, "insert")
+	}
+	sql, param, err := builder.generate("add-rows")
+	if nil != err {
+		return "", nil, err
+	}
+	returning, err := builder.generateReturning()
+	if nil != err {
+		return "", nil, err
+	}
+
+	return builder.dialect.Rebind("INSERT INTO " + builder.dialect.Quote(builder.Table) + sql + returning), param, nil
+}
+
+// BuildInsertBatch 分批生成批量插入sql，每条语句不超过maxRows行且参数个数不超过maxParams，
+// maxRows<=0时取DefaultBatchRows，maxParams<=0时取方言的MaxParams；
+// Sparse为SparseGroup，或为SparseDefault且方言不支持DEFAULT时，按列分组分别生成语句，插入顺序见SparseGroup
+func (builder *SqlBuilder) BuildInsertBatch(maxRows, maxParams int) ([]Statement, error) {
+	if maxRows <= 0 {
+		maxRows = DefaultBatchRows
+	}
+	if maxParams <= 0 || maxParams > builder.dialect.MaxParams() {
+		maxParams = builder.dialect.MaxParams()
+	}
+	originValue := reflect.ValueOf(builder.Model)
+	if originValue.Kind() != reflect.Slice && originValue.Kind() != reflect.Array {
+		return nil, &BuildError{Op: "insert", Row: -1, Detail: fmt.Sprintf("%T", builder.Model), Err: ErrModelKind}
+	}
+	if originValue.Kind() == reflect.Array {
+		rows := reflect.MakeSlice(reflect.SliceOf(originValue.Type().Elem()), originValue.Len(), originValue.Len())
+		reflect.Copy(rows, originValue)
+		originValue = rows
+	}
+	all := *builder
+	all.Model = originValue.Interface()
+	rows, err := all.insertRows()
+	if nil != err {
+		return nil, err
+	}
+
+	// 分组，每组为行号列表
+	grouped := builder.sparse == SparseGroup || builder.sparse == SparseDefault && !builder.dialect.DefaultValue()
+	groups := make([][]int, 0, 1)
+	if grouped {
+		index := make(map[string]int)
+		for j, row := range rows {
+			key := ""
+			for _, c := range row {
+				key += c.column + ","
+			}
+			i, ok := index[key]
+			if !ok {
+				i = len(groups)
+				index[key] = i
+				groups = append(groups, nil)
+			}
+			groups[i] = append(groups[i], j)
+		}
+	} else {
+		group := make([]int, len(rows))
+		for j := range group {
+			group[j] = j
+		}
+		groups = append(groups, group)
+	}
+
+	statements := make([]Statement, 0, len(groups))
+	for _, group := range groups {
+		// 以组内列的并集计算每条语句可容纳的行数
+		exist := make(map[string]bool)
+		for _, j := range group {
+			for _, c := range rows[j] {
+				exist[c.column] = true
+			}
+		}
+		columns := len(exist)
+		if columns > maxParams {
+			return nil, &BuildError{Op: "insert", Row: group[0], Detail: fmt.Sprintf("%d > %d", columns, maxParams), Err: ErrTooManyParams}
+		}
+		size := maxRows
+		if maxParams/columns < size {
+			size = maxParams / columns
+		}
+
+		for start := 0; start < len(group); start += size {
+			end := start + size
+			if end > len(group) {
+				end = len(group)
+			}
+			part := reflect.MakeSlice(originValue.Type(), 0, end-start)
+			for _, j := range group[start:end] {
+				part = reflect.Append(part, originValue.Index(j))
+			}
+			chunk := *builder
+			chunk.Model = part.Interface()
+			if grouped {
+				chunk.sparse = SparseStrict
+			}
+			sql, param, err := chunk.BuildInsert()
+			if nil != err {
+				// 行号换算为整个Model中的行号
+				var e *BuildError
+				if errors.As(err, &e) && e.Row >= 0 {
+					e.Row = group[start+e.Row]
+				}
+				return nil, err
+			}
+			statements = append(statements, Statement{Sql: sql, Params: param})
+		}
+	}
+	return statements, nil
+}
+
+// BuildUpsert 生成插入或更新sql，Model为结构体指针或结构体切片，插入非nil字段，
+// 与冲突键冲突时更新tag带set的列；同名的unique=name列组成一个唯一键，冲突键见OnConflict
+func (builder *SqlBuilder) BuildUpsert() (string, []interface{}, error) {
+	if err := builder.validate(); nil != err {
+		return "", nil, withOp(err, "upsert")
+	}
+	action := "add-row"
+	if t := reflect.TypeOf(builder.Model); nil != t && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		action = "add-rows"
+	}
+	sql, param, err := builder.generate(action)
+	if nil != err {
+		return "", nil, err
+	}
+	upsert, upsertParam, err := builder.generate("upsert")
+	if nil != err {
+		return "", nil, err
+	}
+	param = append(param, upsertParam...)
+	returning, err := builder.generateReturning()
+	if nil != err {
+		return "", nil, err
+	}
+
+	return builder.dialect.Rebind("INSERT INTO " + builder.dialect.Quote(builder.Table) + sql + upsert + returning), param, nil
+}
+
+// OnConflict 指定upsert的冲突键，columns需与pk列或某个唯一索引(同名unique列)的列一致；
+// Model有多个唯一索引时，PostgreSQL及SQLite需指定，MySQL不区分冲突的键
+func (builder *SqlBuilder) OnConflict(columns ...string) *SqlBuilder {
+	builder.conflict = columns
+	return builder
+}
+
+// conflictKey upsert的冲突键
+func (builder *SqlBuilder) conflictKey(t reflect.Type) ([]string, error) {
+	pks := make([]string, 0)
+	for _, f := range getModelMeta(t).fields {
+		if f.has("pk") {
+			pks = append(pks, f.column)
+		}
+	}
+	uniques := builder.uniqueKeys(t)
+	if len(builder.conflict) > 0 {
+		if sameColumns(builder.conflict, pks) {
+			return builder.conflict, nil
+		}
+		for _, idx := range uniques {
+			if sameColumns(builder.conflict, idx.columns) {
+				return builder.conflict, nil
+			}
+		}
+		return nil, &BuildError{Op: "upsert", Row: -1, Detail: strings.Join(builder.conflict, ","), Err: ErrNoUniqueKey}
+	}
+	switch {
+	case len(uniques) == 1 || len(uniques) > 1 && builder.dialect == MySQL:
+		return uniques[0].columns, nil
+	case len(uniques) > 1:
+		names := make([]string, 0, len(uniques))
+		for _, idx := range uniques {
+			names = append(names, idx.name)
+		}
+		return nil, &BuildError{Op: "upsert", Row: -1, Detail: strings.Join(names, ","), Err: ErrAmbiguousKey}
+	case len(pks) > 0:
+		return pks, nil
+	default:
+		return nil, buildErr("upsert", ErrNoUniqueKey, "")
+	}
+}
+
+// sameColumns 两组列是否相同，不区分顺序
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	exist := make(map[string]bool, len(a))
+	for _, c := range a {
+		exist[c] = true
+	}
+	for _, c := range b {
+		if !exist[c] {
+			return false
+		}
+	}
+	return true
+}
+
+// BuildSelect 生成查询sql，查询Model中带tag的列，Cond中非nil字段为条件，按sort字段排序；
+// 关联查询、分组及聚合见As、Join、Columns、GroupBy、Having
+func (builder *SqlBuilder) BuildSelect() (string, []interface{}, error) {
+	sql, param, err := builder.buildSelect()
+	if nil != err {
+		return "", nil, err
+	}
+	return builder.dialect.Rebind(sql), param, nil
+}
+
+// buildSelect 生成?占位符的查询sql
+func (builder *SqlBuilder) buildSelect() (string, []interface{}, error) {
+	if err := builder.validate(); nil != err {
+		return "", nil, withOp(err, "select")
+	}
+	column := builder.generateColumns()
+	if "" == column {
+		var err error
+		if column, _, err = builder.generate("column"); nil != err {
+			return "", nil, err
+		}
+	}
+	from, param, err := builder.generateFrom()
+	if nil != err {
+		return "", nil, withOp(err, "select")
+	}
+	where, whereParam, err := builder.generate("where")
+	if nil != err {
+		return "", nil, withOp(err, "select")
+	}
+	param = append(param, whereParam...)
+	if len(builder.after) > 0 {
+		after, afterParam, err := builder.generate("after")
+		if nil != err {
+			return "", nil, err
+		}
+		if "" != where {
+			where += " AND "
+		}
+		where += after
+		param = append(param, afterParam...)
+	}
+
+	if deleted, deletedParam, _ := builder.generate("not-deleted"); "" != deleted && !builder.unscoped {
+		if "" != where {
+			where += " AND "
+		}
+		where += deleted
+		param = append(param, deletedParam...)
+	}
+
+	sql := "SELECT " + column + " FROM " + from
+	if "" != where {
+		sql += " WHERE " + where
+	}
+	if len(builder.groupBy) > 0 {
+		group := ""
+		for _, c := range builder.groupBy {
+			if "" != group {
+				group += ","
+			}
+			group += builder.dialect.Quote(c)
+		}
+		sql += " GROUP BY " + group
+	}
+	if nil != builder.having {
+		having, havingParam, err := builder.having.build(builder.dialect)
+		if nil != err {
+			return "", nil, withOp(err, "having")
+		}
+		if "" != having {
+			sql += " HAVING " + having
+			param = append(param, havingParam...)
+		}
+	}
+	if sort, _, _ := builder.generate("sort"); "" != sort && (len(builder.groupBy) == 0 || len(builder.orderBy) > 0) {
+		sql += " ORDER BY " + sort
+	}
+	if builder.limit > 0 {
+		sql += fmt.Sprintf(" LIMIT %d", builder.limit)
+		if builder.offset > 0 {
+			sql += fmt.Sprintf(" OFFSET %d", builder.offset)
+		}
+	}
+	return sql, param, nil
+}
+
+// BuildDelete 生成删除sql，Cond中非nil字段为条件；Model中有deleted标记列时生成软删除的UPDATE语句
+func (builder *SqlBuilder) BuildDelete() (string, []interface{}, error) {
+	if err := builder.validate(); nil != err {
+		return "", nil, withOp(err, "delete")
+	}
+	where, param, err := builder.generate("where")
+	if nil != err {
+		return "", nil, withOp(err, "delete")
+	}
+	if "" == where && !builder.fullTable {
+		return "", nil, buildErr("delete", ErrNoCondition, "")
+	}
+	returning, err := builder.generateReturning()
+	if nil != err {
+		return "", nil, err
+	}
+	if nil == builder.deletedField() || builder.unscoped {
+		sql := "DELETE FROM " + builder.dialect.Quote(builder.Table)
+		if "" != where {
+			sql += " WHERE " + where
+		}
+		return builder.dialect.Rebind(sql + returning), param, nil
+	}
+
+	set, setParam, err := builder.generate("deleted")
+	if nil != err {
+		return "", nil, err
 	}
+	deleted, deletedParam, _ := builder.generate("not-deleted")
+	if "" != where {
+		where += " AND "
+	}
+	where += deleted
+	param = append(setParam, append(param, deletedParam...)...)
+	sql := "UPDATE " + builder.dialect.Quote(builder.Table) + " SET " + set + " WHERE " + where
+	return builder.dialect.Rebind(sql + returning), param, nil
+}
 
-	return "INSERT INTO " + builder.Table + sql, param, nil
+// generateReturning 生成RETURNING子句
+func (builder *SqlBuilder) generateReturning() (string, error) {
+	if len(builder.returning) == 0 {
+		return "", nil
+	}
+	if !builder.dialect.Returning() {
+		return "", &BuildError{Op: "returning", Row: -1, Detail: builder.dialect.String(), Err: ErrReturning}
+	}
+	returning := ""
+	for _, c := range builder.returning {
+		if "" != returning {
+			returning += ","
+		}
+		returning += builder.dialect.Quote(c)
+	}
+	return " RETURNING " + returning, nil
 }
 
-// generate 生成sql的列及条件部分，返回 列，条件部分
-func (builder *SqlBuilder) generate(action string) (string, []interface{}) {
+// generate 生成sql的列及条件部分，返回 列，条件部分，错误
+func (builder *SqlBuilder) generate(action string) (string, []interface{}, error) {
 	switch action {
 	case "add-row":
-		column := ""
-		values := ""
+		var column, values strings.Builder
 		params := make([]interface{}, 0)
 		originType := reflect.TypeOf(builder.Model)
-		if originType.Kind() != reflect.Ptr || originType.Elem().Kind() != reflect.Struct {
-			DefaultLogger.Error("param error")
-			return "", params
+		if nil == originType || originType.Kind() != reflect.Ptr || originType.Elem().Kind() != reflect.Struct {
+			return "", nil, &BuildError{Op: "insert", Row: -1, Detail: fmt.Sprintf("%T", builder.Model), Err: ErrModelKind}
 		}
-		originValue := reflect.ValueOf(builder.Model)
+		originValue := reflect.ValueOf(builder.Model).Elem()
 
-		for i := 0; i < originType.Elem().NumField(); i++ {
-			tag := originType.Elem().Field(i).Tag.Get("db")
-			if "" == tag {
-				continue
-			}
-			if originValue.Elem().Field(i).Kind() == reflect.Ptr {
-				if !originValue.Elem().Field(i).IsNil() {
-					if "" != column {
-						column += ","
-						values += ","
-					}
-					if strings.Index(tag, ",") > 0 {
-						column += "`" + tag[:strings.Index(tag, ",")] + "`"
-					} else {
-						column += "`" + tag + "`"
-					}
-					values += "?"
-					params = append(params, originValue.Elem().Field(i).Interface())
+		for _, f := range getModelMeta(originType.Elem()).fields {
+			if v, ok := insertValue(f, originValue); ok {
+				if len(params) > 0 {
+					column.WriteByte(',')
+					values.WriteByte(',')
 				}
+				column.WriteString(f.quote(builder.dialect))
+				values.WriteByte('?')
+				params = append(params, f.param(v))
 			}
 		}
-		if len(column) == 0 {
-			return "", params
+		if len(params) == 0 {
+			return "", nil, buildErr("insert", ErrNoColumns, "")
 		}
-		return fmt.Sprintf("(%s) VALUES (%s)", column, values), params
+		return "(" + column.String() + ") VALUES (" + values.String() + ")", params, nil
 	case "add-rows":
-		column := ""
-		columnCount := make(map[int]int, 10)
-		values := ""
-		params := make([]interface{}, 0)
 		originType := reflect.TypeOf(builder.Model)
-		if originType.Kind() != reflect.Slice && originType.Kind() != reflect.Array ||
-			originType.Elem().Kind() != reflect.Struct {
-			DefaultLogger.Error("param error")
-			return "", params
-		}
-		originValue := reflect.ValueOf(builder.Model)
-		for j := 0; j < originValue.Len(); j++ {
-			item := originValue.Index(j)
-			itemType := item.Type()
-			row := ""
-			for i := 0; i < itemType.NumField(); i++ {
-				tag := itemType.Field(i).Tag.Get("db")
-				if "" == tag {
-					continue
+		if nil == originType || originType.Kind() != reflect.Slice && originType.Kind() != reflect.Array {
+			return "", nil, &BuildError{Op: "insert", Row: -1, Detail: fmt.Sprintf("%T", builder.Model), Err: ErrModelKind}
+		}
+		rows, err := builder.insertRows()
+		if nil != err {
+			return "", nil, err
+		}
+		columns := make([]string, 0, len(rows[0]))
+		fill := ""
+		switch builder.sparse {
+		case SparseDefault, SparseNull:
+			// 所有行的列的并集，按出现的顺序排列
+			fill = "NULL"
+			if builder.sparse == SparseDefault {
+				if !builder.dialect.DefaultValue() {
+					return "", nil, &BuildError{Op: "insert", Row: -1, Detail: builder.dialect.String(), Err: ErrDefaultValue}
 				}
-				if item.Field(i).Kind() == reflect.Ptr {
-					if !item.Field(i).IsNil() {
-						if j == 0 {
-							if i > 0 {
-								column += ","
-							}
-							if strings.Index(tag, ",") > 0 {
-								column += "`" + tag[:strings.Index(tag, ",")] + "`"
-							} else {
-								column += "`" + tag + "`"
-							}
-						}
-
-						if num, ok := columnCount[i]; ok {
-							columnCount[i] = num + 1
-						} else {
-							columnCount[i] = 1
-						}
-						if "" != row {
-							row += ","
-						}
-
-						row += "?"
-						params = append(params, item.Field(i).Interface())
+				fill = "DEFAULT"
+			}
+			exist := make(map[string]bool)
+			for _, row := range rows {
+				for _, c := range row {
+					if !exist[c.column] {
+						exist[c.column] = true
+						columns = append(columns, c.column)
 					}
 				}
 			}
-			if len(row) > 0 {
-				if j > 0 {
-					values += ","
+		default:
+			// 每行的列需与第一行一致
+			for _, c := range rows[0] {
+				columns = append(columns, c.column)
+			}
+			for j, row := range rows {
+				if err := sameRowColumns(columns, row); nil != err {
+					err.Row = j
+					return "", nil, err
 				}
-				values += fmt.Sprintf("(%s)", row)
 			}
 		}
 
-		if len(values) == 0 {
-			DefaultLogger.Error("no data")
-			return "", params
+		var sql strings.Builder
+		sql.WriteByte('(')
+		for i, c := range columns {
+			if i > 0 {
+				sql.WriteByte(',')
+			}
+			sql.WriteString(builder.dialect.Quote(c))
 		}
-		index := 0
-		count := 0
-		for _, v := range columnCount {
-			if index == 0 {
-				count = v
+		sql.WriteString(") VALUES ")
+		row := "(" + strings.TrimSuffix(strings.Repeat("?,", len(columns)), ",") + ")"
+		sql.Grow(len(rows) * (len(row) + 1))
+		params := make([]interface{}, 0, len(rows)*len(columns))
+		for j, r := range rows {
+			if j > 0 {
+				sql.WriteByte(',')
 			}
-			if count != v {
-				log.Println(columnErr)
-				return "", nil
+			if "" == fill {
+				// 各行的列与columns一致
+				sql.WriteString(row)
+				for _, c := range r {
+					params = append(params, c.value)
+				}
+				continue
 			}
-			index++
+			value := make(map[string]interface{}, len(r))
+			for _, c := range r {
+				value[c.column] = c.value
+			}
+			sql.WriteByte('(')
+			for i, c := range columns {
+				if i > 0 {
+					sql.WriteByte(',')
+				}
+				if v, ok := value[c]; ok {
+					sql.WriteByte('?')
+					params = append(params, v)
+				} else {
+					sql.WriteString(fill)
+				}
+			}
+			sql.WriteByte(')')
 		}
-		return fmt.Sprintf("(%s) VALUES %s", column, values), params
+		return sql.String(), params, nil
 	case "set":
 		set := ""
 		params := make([]interface{}, 0)
 		originType := reflect.TypeOf(builder.Model)
-		if originType.Kind() != reflect.Ptr || originType.Elem().Kind() != reflect.Struct {
-			DefaultLogger.Error("param error")
-			return set, params
+		if nil != builder.changes {
+			if builder.changes.Len() == 0 {
+				return "", nil, buildErr("update", ErrNoColumns, "")
+			}
+			for _, c := range builder.changes.columns {
+				v := builder.changes.values[c]
+				if t := builder.modelType(); nil != t && nil != getModelMeta(t).columns[c] {
+					f := getModelMeta(t).columns[c]
+					if f.has("version") {
+						// version列始终自增，忽略Changeset中的值
+						continue
+					}
+					v = f.param(v)
+				}
+				if "" != set {
+					set += ","
+				}
+				set += builder.dialect.Quote(c) + "=?"
+				params = append(params, v)
+			}
+			if nil == originType {
+				return set, params, nil
+			}
+		}
+		if nil == originType || originType.Kind() != reflect.Ptr || originType.Elem().Kind() != reflect.Struct {
+			return "", nil, &BuildError{Op: "update", Row: -1, Detail: fmt.Sprintf("%T", builder.Model), Err: ErrModelKind}
 		}
-		originValue := reflect.ValueOf(builder.Model)
+		originValue := reflect.ValueOf(builder.Model).Elem()
 
-		for i := 0; i < originType.Elem().NumField(); i++ {
-			tag := originType.Elem().Field(i).Tag.Get("db")
-			if "" == tag {
-				continue
-			}
-			if strings.Contains(tag, "set") {
-				if !originValue.Elem().Field(i).IsNil() {
+		for _, f := range getModelMeta(originType.Elem()).fields {
+			v, ok := f.present(originValue)
+			switch {
+			case f.has("version"):
+				if "" != set {
+					set += ","
+				}
+				set += builder.dialect.Quote(f.column) + "=" + builder.dialect.Quote(f.column) + "+1"
+			case nil != builder.changes && builder.changes.Has(f.column):
+				// 已由Changeset设置
+			case f.has("updated"):
+				// 更新时间始终取当前时间，不使用Model中读出的旧值
+				if now, ok := nowValue(f.typ); ok {
 					if "" != set {
 						set += ","
 					}
-					if strings.Index(tag, ",") > 0 {
-						set += "`" + tag[:strings.Index(tag, ",")] + "`=?"
-					} else {
-						set += "`" + tag + "`=?"
-					}
-					params = append(params, originValue.Elem().Field(i).Interface())
+					set += builder.dialect.Quote(f.column) + "=?"
+					params = append(params, f.param(now))
 				}
+			case f.updatable() && ok && nil == builder.changes:
+				if "" != set {
+					set += ","
+				}
+				set += builder.dialect.Quote(f.column) + "=?"
+				params = append(params, f.param(v.Interface()))
+			}
+		}
+		if "" == set {
+			return "", nil, buildErr("update", ErrNoColumns, "")
+		}
+		return set, params, nil
+	case "version":
+		// 乐观锁条件，Model中version列的当前值
+		params := make([]interface{}, 0)
+		originType := reflect.TypeOf(builder.Model)
+		if nil == originType || originType.Kind() != reflect.Ptr || originType.Elem().Kind() != reflect.Struct {
+			return "", params, nil
+		}
+		originValue := reflect.ValueOf(builder.Model).Elem()
+		for _, f := range getModelMeta(originType.Elem()).fields {
+			if !f.has("version") {
+				continue
+			}
+			// version列不需要omitempty，非空时作为条件
+			if v := f.value(originValue); v.IsValid() && !v.IsZero() {
+				params = append(params, v.Interface())
+				return builder.dialect.Quote(f.column) + "=?", params, nil
+			}
+		}
+		return "", params, nil
+	case "where":
+		if nil == builder.Cond {
+			return "", make([]interface{}, 0), nil
+		}
+		expr, err := condExpr(builder.Cond, builder.alias)
+		if nil != err {
+			return "", nil, err
+		}
+		return expr.build(builder.dialect)
+	case "column":
+		column := ""
+		originType := builder.modelType()
+		if nil == originType {
+			return "", nil, &BuildError{Op: "select", Row: -1, Detail: fmt.Sprintf("%T", builder.Model), Err: ErrModelKind}
+		}
+		if len(builder.pick) > 0 {
+			for _, c := range builder.pick {
+				if "" != column {
+					column += ","
+				}
+				column += builder.quoteColumn(c)
+			}
+			return column, nil, nil
+		}
+		for _, f := range getModelMeta(originType).fields {
+			if "" != column {
+				column += ","
 			}
+			column += builder.quoteColumn(f.column)
 		}
-		return set, params
+		if "" == column {
+			return "", nil, buildErr("select", ErrNoColumns, "")
+		}
+		return column, nil, nil
+	case "sort":
+		sort := ""
+		for _, c := range builder.sortColumns() {
+			if "" != sort {
+				sort += ","
+			}
+			if c.desc {
+				sort += builder.quoteColumn(c.column) + " DESC"
+			} else {
+				sort += builder.quoteColumn(c.column) + " ASC"
+			}
+		}
+		return sort, nil, nil
+	case "after":
+		// (a>?) OR (a=? AND b>?) ...，兼容不同排序方向的多列游标
+		after := ""
+		params := make([]interface{}, 0)
+		columns := builder.sortColumns()
+		if len(columns) == 0 || len(columns) != len(builder.after) {
+			return "", nil, &BuildError{Op: "select", Row: -1, Detail: fmt.Sprintf("%d values, %d sort columns", len(builder.after), len(columns)), Err: ErrCursor}
+		}
+		for i, c := range columns {
+			if "" != after {
+				after += " OR "
+			}
+			item := ""
+			for j := 0; j < i; j++ {
+				item += builder.quoteColumn(columns[j].column) + "=? AND "
+				params = append(params, builder.after[j])
+			}
+			if c.desc {
+				item += builder.quoteColumn(c.column) + "<?"
+			} else {
+				item += builder.quoteColumn(c.column) + ">?"
+			}
+			params = append(params, builder.after[i])
+			after += "(" + item + ")"
+		}
+		return "(" + after + ")", params, nil
+	case "upsert":
+		// 冲突键为OnConflict指定的键，未指定时取唯一的unique键，无unique键时取pk列；
+		// 更新列为第一行中非nil且带set的非冲突键、非pk列，updated列更新为当前时间
+		originType := builder.modelType()
+		if nil == originType {
+			return "", nil, &BuildError{Op: "upsert", Row: -1, Detail: fmt.Sprintf("%T", builder.Model), Err: ErrModelKind}
+		}
+		originValue := reflect.Indirect(reflect.ValueOf(builder.Model))
+		if originValue.Kind() == reflect.Slice || originValue.Kind() == reflect.Array {
+			if originValue.Len() == 0 {
+				return "", nil, buildErr("upsert", ErrEmptySlice, "")
+			}
+			originValue = rowValue(originValue.Index(0))
+		}
+		keys, err := builder.conflictKey(originType)
+		if nil != err {
+			return "", nil, err
+		}
+		key := make(map[string]bool, len(keys))
+		for _, k := range keys {
+			key[k] = true
+		}
+		columns := make([]string, 0)
+		updated := make([]string, 0)
+		params := make([]interface{}, 0)
+		for _, f := range getModelMeta(originType).fields {
+			if key[f.column] || f.has("pk") {
+				continue
+			}
+			if f.has("updated") {
+				// 插入的值可能是读出的旧值，更新时取当前时间
+				if now, ok := nowValue(f.typ); ok {
+					updated = append(updated, f.column)
+					params = append(params, f.param(now))
+				}
+				continue
+			}
+			if _, ok := f.present(originValue); ok && f.has("set") && !f.has("version") {
+				columns = append(columns, f.column)
+			}
+		}
+		return builder.dialect.upsert(keys, columns, updated), params, nil
+	case "deleted":
+		// 软删除，按字段类型写入删除时间或标记
+		params := make([]interface{}, 0)
+		f := builder.deletedField()
+		if nil == f {
+			return "", params, nil
+		}
+		t := f.typ
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if now, ok := nowValue(t); ok {
+			params = append(params, now)
+		} else if t.Kind() == reflect.Bool {
+			params = append(params, true)
+		} else {
+			return "", nil, &BuildError{Op: "delete", Field: f.name, Row: -1, Detail: f.typ.String(), Err: ErrSoftDelete}
+		}
+		return builder.dialect.Quote(f.column) + "=?", params, nil
+	case "not-deleted":
+		// 未删除条件，指针类型为NULL，其他类型为零值
+		params := make([]interface{}, 0)
+		f := builder.deletedField()
+		if nil == f {
+			return "", params, nil
+		}
+		if f.typ.Kind() == reflect.Ptr {
+			return builder.quoteColumn(f.column) + " IS NULL", params, nil
+		}
+		params = append(params, reflect.Zero(f.typ).Interface())
+		return builder.quoteColumn(f.column) + "=?", params, nil
 	default:
-		return "", nil
+		return "", nil, nil
 	}
-	return "", nil
 }
 
-func XormUpdateParam(model interface{}) (map[string]interface{}, error) {
-	params := make(map[string]interface{}, 0)
-	originType := reflect.TypeOf(model)
-	if originType.Kind() != reflect.Ptr || originType.Elem().Kind() != reflect.Struct {
-		DefaultLogger.Error("param error")
-		return nil, errors.New("param error")
+// sameRowColumns 校验行的列与columns一致，不一致时Field为第一个缺少的列，没有缺少的列时为第一个多出的列
+func sameRowColumns(columns []string, row []insertColumn) *BuildError {
+	same := len(row) == len(columns)
+	for i := 0; same && i < len(row); i++ {
+		same = columns[i] == row[i].column
+	}
+	if same {
+		return nil
 	}
-	originValue := reflect.ValueOf(model)
+	exist := make(map[string]bool, len(row))
+	for _, c := range row {
+		exist[c.column] = true
+	}
+	for _, c := range columns {
+		if !exist[c] {
+			return &BuildError{Op: "insert", Field: c, Detail: "missing column", Err: ErrInconsistentColumns}
+		}
+	}
+	expected := make(map[string]bool, len(columns))
+	for _, c := range columns {
+		expected[c] = true
+	}
+	for _, c := range row {
+		if !expected[c.column] {
+			return &BuildError{Op: "insert", Field: c.column, Detail: "unexpected column", Err: ErrInconsistentColumns}
+		}
+	}
+	for i, c := range row {
+		if columns[i] != c.column {
+			return &BuildError{Op: "insert", Field: columns[i], Detail: "got " + c.column, Err: ErrInconsistentColumns}
+		}
+	}
+	return nil
+}
 
-	for i := 0; i < originType.Elem().NumField(); i++ {
-		if originType.Elem().Field(i).Type.Kind() != reflect.Ptr {
-			continue
+// versionChecked 更新语句是否带乐观锁条件
+func (builder *SqlBuilder) versionChecked() bool {
+	version, _, _ := builder.generate("version")
+	return "" != version
+}
+
+// insertRows 批量插入的每一行的列及值，Model需为非空的结构体(指针)切片
+func (builder *SqlBuilder) insertRows() ([][]insertColumn, error) {
+	originValue := reflect.ValueOf(builder.Model)
+	if originValue.Len() == 0 {
+		return nil, buildErr("insert", ErrEmptySlice, "")
+	}
+	rows := make([][]insertColumn, 0, originValue.Len())
+	for j := 0; j < originValue.Len(); j++ {
+		item := rowValue(originValue.Index(j))
+		if item.Kind() != reflect.Struct {
+			return nil, &BuildError{Op: "insert", Row: j, Detail: item.Kind().String(), Err: ErrModelKind}
 		}
-		if !originValue.Elem().Field(i).IsNil() {
-			tag := originType.Elem().Field(i).Tag.Get("db")
-			if "" == tag {
-				continue
+		row := make([]insertColumn, 0)
+		for _, f := range getModelMeta(item.Type()).fields {
+			if v, ok := insertValue(f, item); ok {
+				row = append(row, insertColumn{column: f.column, value: f.param(v)})
 			}
-			if strings.Index(tag, ",") > 0 {
-				params[tag[:strings.Index(tag, ",")]] = originValue.Elem().Field(i).Interface()
-			} else {
-				params[tag] = originValue.Elem().Field(i).Interface()
+		}
+		if len(row) == 0 {
+			return nil, rowErr("insert", ErrNoColumns, j, "")
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// insertValue 插入时字段的值，created/updated列为空时取当前时间，version列为空时取1，这些列不需要omitempty；
+// 零值的pk/auto值字段不写入，由数据库生成
+func insertValue(f *fieldMeta, v reflect.Value) (interface{}, bool) {
+	if f.has("created") || f.has("updated") || f.has("version") {
+		field := f.value(v)
+		if field.IsValid() && !field.IsZero() {
+			return field.Interface(), true
+		}
+		t := f.typ
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if f.has("version") && t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64 {
+			return reflect.ValueOf(1).Convert(t).Interface(), true
+		}
+		if now, isTime := nowValue(t); isTime && !f.has("version") {
+			return now, true
+		}
+	}
+	field, ok := f.present(v)
+	if !ok {
+		return nil, false
+	}
+	if (f.has("pk") || f.has("auto")) && field.Kind() != reflect.Ptr && field.IsZero() {
+		return nil, false
+	}
+	return field.Interface(), true
+}
+
+// nowValue 按字段类型取当前时间，time.Time取当前时间，整数类型取unix秒
+func nowValue(t reflect.Type) (interface{}, bool) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	now := time.Now()
+	switch {
+	case t == reflect.TypeOf(now):
+		return now, true
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return reflect.ValueOf(now.Unix()).Convert(t).Interface(), true
+	default:
+		return nil, false
+	}
+}
+
+// modelType Model对应的结构体类型，Model需为结构体、结构体指针或其切片，[]interface{}取第一行的类型
+func (builder *SqlBuilder) modelType() reflect.Type {
+	if nil == builder.Model {
+		return nil
+	}
+	t := reflect.TypeOf(builder.Model)
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+		if t.Kind() == reflect.Interface {
+			v := reflect.ValueOf(builder.Model)
+			if v.Len() == 0 {
+				return nil
 			}
+			t = rowValue(v.Index(0)).Type()
+		}
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
+
+// rowValue 去除interface及指针包装后的行
+func rowValue(v reflect.Value) reflect.Value {
+	for (v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr) && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v
+}
+
+// sortColumns 排序列，OrderBy设置的列优先，否则为Model中tag带sort的列
+func (builder *SqlBuilder) sortColumns() []sortColumn {
+	if len(builder.orderBy) > 0 {
+		return builder.orderBy
+	}
+	originType := builder.modelType()
+	if nil == originType {
+		return nil
+	}
+	return getModelMeta(originType).sorts
+}
+
+// deletedField Model中tag带deleted的软删除标记字段
+func (builder *SqlBuilder) deletedField() *fieldMeta {
+	originType := builder.modelType()
+	if nil == originType {
+		return nil
+	}
+	return getModelMeta(originType).deleted
+}
+
+// XormUpdateParam xorm的更新map，model中非nil的指针字段为更新列，没有更新列时返回nil
+//
+// Deprecated: 使用ChangesetOf(model)及Changeset.Xorm，Changeset还支持更新为NULL及Diff
+func XormUpdateParam(model interface{}) (map[string]interface{}, error) {
+	originType := reflect.TypeOf(model)
+	if nil == originType || originType.Kind() != reflect.Ptr || originType.Elem().Kind() != reflect.Struct {
+		return nil, &BuildError{Op: "changeset", Row: -1, Detail: fmt.Sprintf("%T", model), Err: ErrModelKind}
+	}
+	originValue := reflect.ValueOf(model).Elem()
+	c := NewChangeset()
+	for _, f := range getModelMeta(originType.Elem()).fields {
+		if v := f.value(originValue); v.Kind() == reflect.Ptr && !v.IsNil() {
+			c.Set(f.column, v.Interface())
 		}
 	}
-	if len(params) == 0 {
+	if c.Len() == 0 {
 		return nil, nil
 	}
-	return params, nil
+	return c.Xorm(), nil
 }
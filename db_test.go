@@ -0,0 +1,341 @@
+package pocket
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type upsertContact struct {
+	ID    *int64  `db:"id,pk"`
+	Email *string `db:"email,unique"`
+	Phone *string `db:"phone,unique"`
+	Name  *string `db:"name,set"`
+}
+
+type upsertMember struct {
+	ID   *int64  `db:"id,pk"`
+	Org  *int64  `db:"org_id,unique=uk_org_user"`
+	User *int64  `db:"user_id,unique=uk_org_user"`
+	Name *string `db:"name,set"`
+}
+
+func TestBuildUpsertConflictKey(t *testing.T) {
+	id, email, phone, name := int64(1), "a@b.c", "1", "n"
+	contact := &upsertContact{ID: &id, Email: &email, Phone: &phone, Name: &name}
+
+	_, _, err := NewSqlBuilder("q", contact, PostgreSQL).BuildUpsert()
+	if !errors.Is(err, ErrAmbiguousKey) {
+		t.Fatalf("postgres upsert with 2 unique keys err = %v, want ErrAmbiguousKey", err)
+	}
+	sql, _, err := NewSqlBuilder("q", contact, PostgreSQL).OnConflict("email").BuildUpsert()
+	want := `INSERT INTO "q"("id","email","phone","name") VALUES ($1,$2,$3,$4) ON CONFLICT ("email") DO UPDATE SET "name"=EXCLUDED."name"`
+	if nil != err || sql != want {
+		t.Errorf("postgres upsert = %s, %v, want %s", sql, err, want)
+	}
+	sql, _, err = NewSqlBuilder("q", contact, SQLite).OnConflict("id").BuildUpsert()
+	want = `INSERT INTO "q"("id","email","phone","name") VALUES (?,?,?,?) ON CONFLICT ("id") DO UPDATE SET "name"=EXCLUDED."name"`
+	if nil != err || sql != want {
+		t.Errorf("sqlite upsert = %s, %v, want %s", sql, err, want)
+	}
+	if _, _, err = NewSqlBuilder("q", contact, SQLite).OnConflict("email", "phone").BuildUpsert(); !errors.Is(err, ErrNoUniqueKey) {
+		t.Errorf("sqlite upsert on non-key err = %v, want ErrNoUniqueKey", err)
+	}
+	if _, _, err = NewSqlBuilder("q", contact, MySQL).BuildUpsert(); nil != err {
+		t.Errorf("mysql upsert err = %v", err)
+	}
+
+	org, user := int64(2), int64(3)
+	member := &upsertMember{ID: &id, Org: &org, User: &user, Name: &name}
+	sql, _, err = NewSqlBuilder("m", member, PostgreSQL).BuildUpsert()
+	want = `INSERT INTO "m"("id","org_id","user_id","name") VALUES ($1,$2,$3,$4) ON CONFLICT ("org_id","user_id") DO UPDATE SET "name"=EXCLUDED."name"`
+	if nil != err || sql != want {
+		t.Errorf("postgres composite upsert = %s, %v, want %s", sql, err, want)
+	}
+	ddl, err := NewSqlBuilder("m", member, PostgreSQL).BuildCreateTable()
+	if nil != err || len(ddl) != 2 || ddl[1] != `CREATE UNIQUE INDEX "uk_org_user" ON "m" ("org_id","user_id")` {
+		t.Errorf("composite unique index = %v, %v", ddl, err)
+	}
+}
+
+type valueBase struct {
+	ID        int64 `db:"id,pk,auto,zero"`
+	CreatedAt int64 `db:"created_at,created"`
+}
+
+type valueItem struct {
+	valueBase
+	Name  string `db:"name,set,omitempty"`
+	Count int    `db:"count,set,zero"`
+	Note  string `db:"note,set"`
+}
+
+func TestValueFields(t *testing.T) {
+	item := &valueItem{Name: "a", Note: "ignored"}
+	sql, params, err := NewSqlBuilder("p", item, PostgreSQL).BuildInsertRow()
+	if nil != err || sql != `INSERT INTO "p"("created_at","name","count") VALUES ($1,$2,$3)` || params[1] != "a" || params[2] != 0 {
+		t.Errorf("insert = %s, %v, %v", sql, params, err)
+	}
+	item.ID = 5
+	if sql, _, _ = NewSqlBuilder("p", item, PostgreSQL).BuildInsertRow(); sql != `INSERT INTO "p"("id","created_at","name","count") VALUES ($1,$2,$3,$4)` {
+		t.Errorf("insert with id = %s", sql)
+	}
+
+	item.Name = ""
+	sql, params, err = NewSqlBuilder("p", item).Condition(Eq("id", 5)).BuildUpdate()
+	if nil != err || sql != "UPDATE `p` SET `count`=? WHERE `id`=?" || len(params) != 2 {
+		t.Errorf("update = %s, %v, %v", sql, params, err)
+	}
+
+	cond := &valueItem{Name: "a"}
+	sql, params, err = NewSqlBuilder("p", &valueItem{}).Condition(cond).BuildSelect()
+	want := "SELECT `id`,`created_at`,`name`,`count`,`note` FROM `p` WHERE `id`=? AND `name`=? AND `count`=?"
+	if nil != err || sql != want || len(params) != 3 {
+		t.Errorf("select = %s, %v, %v, want %s", sql, params, err, want)
+	}
+
+	c, err := ChangesetOf(&valueItem{Name: "a", Note: "x"})
+	if nil != err || !c.Has("name") || !c.Has("count") || c.Has("note") || c.Has("id") {
+		t.Errorf("changeset columns = %v, %v", c.Columns(), err)
+	}
+}
+
+type sparseRow struct {
+	Name    *string `db:"name"`
+	Age     *int    `db:"age"`
+	Version *int64  `db:"version"`
+}
+
+func TestInconsistentColumns(t *testing.T) {
+	name, age, version := "a", 1, int64(1)
+	cases := []struct {
+		rows   []sparseRow
+		field  string
+		detail string
+	}{
+		{[]sparseRow{{&name, &age, &version}, {&name, nil, &version}}, "age", "missing column"},
+		{[]sparseRow{{&name, nil, nil}, {&name, &age, nil}}, "age", "unexpected column"},
+	}
+	for _, c := range cases {
+		_, _, err := NewSqlBuilder("s", c.rows).BuildInsert()
+		var e *BuildError
+		if !errors.As(err, &e) || !errors.Is(err, ErrInconsistentColumns) || e.Row != 1 || e.Field != c.field || e.Detail != c.detail {
+			t.Errorf("err = %v, want row 1 field %q %s", err, c.field, c.detail)
+		}
+	}
+}
+
+type changesetUser struct {
+	ID      *int64  `db:"id,pk,set"`
+	Name    *string `db:"name,set"`
+	Created *int64  `db:"created_at,created,set"`
+	Version *int64  `db:"version,version,set"`
+}
+
+func TestChangesetOfUpdate(t *testing.T) {
+	id, name, created, version := int64(1), "n", int64(100), int64(3)
+	u := &changesetUser{ID: &id, Name: &name, Created: &created, Version: &version}
+	c, err := ChangesetOf(u)
+	if nil != err || !reflect.DeepEqual(c.Columns(), []string{"name"}) {
+		t.Fatalf("changeset columns = %v, %v", c.Columns(), err)
+	}
+	sql, params, err := NewSqlBuilder("user", u).Changes(c).Condition(Eq("id", id)).BuildUpdate()
+	want := "UPDATE `user` SET `name`=?,`version`=`version`+1 WHERE `id`=? AND `version`=?"
+	if nil != err || sql != want || len(params) != 3 || params[1] != id || *params[2].(*int64) != version {
+		t.Errorf("update = %s, %v, %v, want %s", sql, params, err, want)
+	}
+
+	// Changeset中的version被忽略
+	c.Set("version", int64(3))
+	if sql, _, err = NewSqlBuilder("user", u).Changes(c).Condition(Eq("id", id)).BuildUpdate(); nil != err || sql != want {
+		t.Errorf("update with version in changeset = %s, %v, want %s", sql, err, want)
+	}
+}
+
+type stampedUser struct {
+	ID      *int64     `db:"id,pk"`
+	Name    *string    `db:"name,set"`
+	Updated *time.Time `db:"updated_at,updated"`
+}
+
+func TestUpdatedColumn(t *testing.T) {
+	id, name := int64(1), "n"
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	u := &stampedUser{ID: &id, Name: &name, Updated: &old}
+	start := time.Now()
+
+	sql, params, err := NewSqlBuilder("user", u).Condition(Eq("id", id)).BuildUpdate()
+	if nil != err || sql != "UPDATE `user` SET `name`=?,`updated_at`=? WHERE `id`=?" {
+		t.Fatalf("update = %s, %v", sql, err)
+	}
+	if now, ok := params[1].(time.Time); !ok || now.Before(start) {
+		t.Errorf("update updated_at = %v, want current time", params[1])
+	}
+
+	cases := []struct {
+		dialect Dialect
+		want    string
+	}{
+		{MySQL, "INSERT INTO `user`(`id`,`name`,`updated_at`) VALUES (?,?,?) ON DUPLICATE KEY UPDATE `name`=VALUES(`name`),`updated_at`=?"},
+		{PostgreSQL, `INSERT INTO "user"("id","name","updated_at") VALUES ($1,$2,$3) ON CONFLICT ("id") DO UPDATE SET "name"=EXCLUDED."name","updated_at"=$4`},
+		{SQLite, `INSERT INTO "user"("id","name","updated_at") VALUES (?,?,?) ON CONFLICT ("id") DO UPDATE SET "name"=EXCLUDED."name","updated_at"=?`},
+	}
+	for _, c := range cases {
+		sql, params, err = NewSqlBuilder("user", u, c.dialect).BuildUpsert()
+		if nil != err || sql != c.want || len(params) != 4 {
+			t.Errorf("%s upsert = %s, %v, %v, want %s", c.dialect, sql, params, err, c.want)
+			continue
+		}
+		if *params[2].(*time.Time) != old {
+			t.Errorf("%s upsert insert value = %v, want the model value", c.dialect, params[2])
+		}
+		if now, ok := params[3].(time.Time); !ok || now.Before(start) {
+			t.Errorf("%s upsert updated_at = %v, want current time", c.dialect, params[3])
+		}
+	}
+}
+
+func TestDiffUpdatable(t *testing.T) {
+	id, other, created, version := int64(1), int64(2), int64(100), int64(3)
+	a, b := "a", "b"
+	before := &changesetUser{ID: &id, Name: &a, Created: &created, Version: &version}
+	after := &changesetUser{ID: &other, Name: &b, Version: &other}
+	c, err := Diff(before, after)
+	if nil != err || !reflect.DeepEqual(c.Columns(), []string{"name"}) {
+		t.Fatalf("diff columns = %v, %v", c.Columns(), err)
+	}
+	if m := c.Xorm(); len(m) != 1 || *m["name"].(*string) != "b" {
+		t.Errorf("xorm map = %v", m)
+	}
+	sql, _, err := NewSqlBuilder("user", after).Changes(c).Condition(Eq("id", id)).BuildUpdate()
+	want := "UPDATE `user` SET `name`=?,`version`=`version`+1 WHERE `id`=? AND `version`=?"
+	if nil != err || sql != want {
+		t.Errorf("update = %s, %v, want %s", sql, err, want)
+	}
+
+	c, err = Diff(after, &changesetUser{ID: &other, Version: &other})
+	if nil != err || !reflect.DeepEqual(c.Columns(), []string{"name"}) {
+		t.Fatalf("diff to nil columns = %v, %v", c.Columns(), err)
+	}
+	if v, _ := c.Get("name"); nil != v {
+		t.Errorf("diff to nil value = %v, want NULL", v)
+	}
+}
+
+func TestSparse(t *testing.T) {
+	a, d, age1, age2 := "a", "d", 1, 2
+	rows := []sparseRow{{Name: &a}, {Name: &a, Age: &age1}, {Age: &age2}, {Name: &d}}
+	union := func(fill string) string {
+		return "INSERT INTO `s`(`name`,`age`) VALUES (?," + fill + "),(?,?),(" + fill + ",?),(?," + fill + ")"
+	}
+	cases := []struct {
+		name    string
+		dialect Dialect
+		sparse  Sparse
+		insert  string
+		err     error
+		batch   []string
+	}{
+		{"strict", MySQL, SparseStrict, "", ErrInconsistentColumns, nil},
+		{"null", MySQL, SparseNull, union("NULL"), nil, []string{union("NULL")}},
+		{"default", MySQL, SparseDefault, union("DEFAULT"), nil, []string{union("DEFAULT")}},
+		{"group", MySQL, SparseGroup, "", ErrInconsistentColumns, []string{
+			"INSERT INTO `s`(`name`) VALUES (?),(?)",
+			"INSERT INTO `s`(`name`,`age`) VALUES (?,?)",
+			"INSERT INTO `s`(`age`) VALUES (?)",
+		}},
+		{"sqlite default", SQLite, SparseDefault, "", ErrDefaultValue, []string{
+			`INSERT INTO "s"("name") VALUES (?),(?)`,
+			`INSERT INTO "s"("name","age") VALUES (?,?)`,
+			`INSERT INTO "s"("age") VALUES (?)`,
+		}},
+	}
+	for _, c := range cases {
+		sql, params, err := NewSqlBuilder("s", rows, c.dialect).Sparse(c.sparse).BuildInsert()
+		if nil != c.err {
+			if !errors.Is(err, c.err) {
+				t.Errorf("%s insert err = %v, want %v", c.name, err, c.err)
+			}
+		} else if nil != err || sql != c.insert || len(params) != 5 {
+			t.Errorf("%s insert = %s, %v, %v, want %s", c.name, sql, params, err, c.insert)
+		}
+
+		statements, err := NewSqlBuilder("s", rows, c.dialect).Sparse(c.sparse).BuildInsertBatch(0, 0)
+		if nil == c.batch {
+			if !errors.Is(err, c.err) {
+				t.Errorf("%s batch err = %v, want %v", c.name, err, c.err)
+			}
+			continue
+		}
+		if nil != err || len(statements) != len(c.batch) {
+			t.Errorf("%s batch = %v, %v, want %d statements", c.name, statements, err, len(c.batch))
+			continue
+		}
+		for i, s := range statements {
+			if s.Sql != c.batch[i] {
+				t.Errorf("%s batch %d = %s, want %s", c.name, i, s.Sql, c.batch[i])
+			}
+		}
+		if len(statements) == 3 && (*statements[0].Params[0].(*string) != "a" || *statements[0].Params[1].(*string) != "d") {
+			t.Errorf("%s first group params = %v, want rows 1 and 4", c.name, statements[0].Params)
+		}
+	}
+}
+
+type batchRow struct {
+	Name *string `db:"name"`
+	Age  *int    `db:"age"`
+}
+
+// batchRows n行两列的数据
+func batchRows(n int) []batchRow {
+	name, age := "n", 1
+	rows := make([]batchRow, n)
+	for i := range rows {
+		rows[i] = batchRow{Name: &name, Age: &age}
+	}
+	return rows
+}
+
+func TestBuildInsertBatch(t *testing.T) {
+	cases := []struct {
+		name      string
+		dialect   Dialect
+		rows      int
+		maxRows   int
+		maxParams int
+		sizes     []int
+	}{
+		{"default rows", MySQL, 2500, 0, 0, []int{DefaultBatchRows, DefaultBatchRows, 500}},
+		{"max rows", MySQL, 5, 2, 0, []int{2, 2, 1}},
+		{"max params", PostgreSQL, 5, 10, 6, []int{3, 2}},
+		{"max params odd", MySQL, 5, 10, 5, []int{2, 2, 1}},
+		{"both", MySQL, 7, 3, 4, []int{2, 2, 2, 1}},
+		{"clamp to dialect", SQLite, 20000, 100000, 1000000, []int{16383, 3617}},
+	}
+	for _, c := range cases {
+		statements, err := NewSqlBuilder("b", batchRows(c.rows), c.dialect).BuildInsertBatch(c.maxRows, c.maxParams)
+		if nil != err || len(statements) != len(c.sizes) {
+			t.Errorf("%s = %d statements, %v, want %d", c.name, len(statements), err, len(c.sizes))
+			continue
+		}
+		for i, s := range statements {
+			if len(s.Params) != 2*c.sizes[i] {
+				t.Errorf("%s statement %d has %d params, want %d", c.name, i, len(s.Params), 2*c.sizes[i])
+			}
+		}
+	}
+
+	statements, err := NewSqlBuilder("b", batchRows(3), PostgreSQL).BuildInsertBatch(2, 0)
+	want := []string{`INSERT INTO "b"("name","age") VALUES ($1,$2),($3,$4)`, `INSERT INTO "b"("name","age") VALUES ($1,$2)`}
+	if nil != err || len(statements) != 2 || statements[0].Sql != want[0] || statements[1].Sql != want[1] {
+		t.Errorf("postgres batch = %v, %v, want %v", statements, err, want)
+	}
+
+	_, err = NewSqlBuilder("b", batchRows(3)).BuildInsertBatch(0, 1)
+	var e *BuildError
+	if !errors.Is(err, ErrTooManyParams) || !errors.As(err, &e) || e.Row != 0 {
+		t.Errorf("too many params err = %v, want ErrTooManyParams at row 0", err)
+	}
+}
@@ -0,0 +1,289 @@
+// Package pocket Create at 2026-10-18 11:20
+package pocket
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const defaultVarcharSize = 255
+
+// columnDef 列定义
+type columnDef struct {
+	name    string
+	typ     string
+	notNull bool
+	def     string
+	pk      bool
+	auto    bool
+}
+
+// indexDef 索引定义
+type indexDef struct {
+	name    string
+	unique  bool
+	columns []string
+}
+
+// BuildCreateTable 根据Model生成建表及建索引语句，tag选项：pk主键，auto自增，size=n字符串长度(默认255)，
+// type=xxx指定列类型，null/notnull可空性(默认指针可空，其他不可空)，default=xxx默认值，
+// index/index=name普通索引，unique/unique=name唯一索引，同名索引合并为联合索引
+func (builder *SqlBuilder) BuildCreateTable() ([]string, error) {
+	columns, indexes, err := builder.tableDef(builder.Model)
+	if nil != err {
+		return nil, err
+	}
+	pk := ""
+	body := ""
+	for _, c := range columns {
+		if "" != body {
+			body += ","
+		}
+		body += builder.columnSql(c)
+		if c.pk && !(c.auto && builder.dialect == SQLite) {
+			if "" != pk {
+				pk += ","
+			}
+			pk += builder.dialect.Quote(c.name)
+		}
+	}
+	if "" != pk {
+		body += ",PRIMARY KEY (" + pk + ")"
+	}
+
+	list := []string{"CREATE TABLE " + builder.dialect.Quote(builder.Table) + " (" + body + ")"}
+	for _, idx := range indexes {
+		list = append(list, builder.createIndexSql(idx))
+	}
+	return list, nil
+}
+
+// BuildMigration 对比旧版本结构体old与Model，生成ALTER TABLE迁移语句，
+// 依次为删除索引、删除列、新增列、修改列、新增索引，主键变更不处理
+func (builder *SqlBuilder) BuildMigration(old interface{}) ([]string, error) {
+	oldColumns, oldIndexes, err := builder.tableDef(old)
+	if nil != err {
+		return nil, err
+	}
+	columns, indexes, err := builder.tableDef(builder.Model)
+	if nil != err {
+		return nil, err
+	}
+	table := "ALTER TABLE " + builder.dialect.Quote(builder.Table)
+	list := make([]string, 0)
+
+	indexMap := make(map[string]indexDef, len(indexes))
+	for _, idx := range indexes {
+		indexMap[idx.name] = idx
+	}
+	oldIndexMap := make(map[string]indexDef, len(oldIndexes))
+	for _, idx := range oldIndexes {
+		oldIndexMap[idx.name] = idx
+		if n, ok := indexMap[idx.name]; !ok || !sameIndex(n, idx) {
+			if builder.dialect == MySQL {
+				list = append(list, "DROP INDEX "+builder.dialect.Quote(idx.name)+" ON "+builder.dialect.Quote(builder.Table))
+			} else {
+				list = append(list, "DROP INDEX "+builder.dialect.Quote(idx.name))
+			}
+		}
+	}
+
+	columnMap := make(map[string]columnDef, len(columns))
+	for _, c := range columns {
+		columnMap[c.name] = c
+	}
+	oldColumnMap := make(map[string]columnDef, len(oldColumns))
+	for _, c := range oldColumns {
+		oldColumnMap[c.name] = c
+		if _, ok := columnMap[c.name]; !ok {
+			list = append(list, table+" DROP COLUMN "+builder.dialect.Quote(c.name))
+		}
+	}
+	for _, c := range columns {
+		if _, ok := oldColumnMap[c.name]; !ok {
+			list = append(list, table+" ADD COLUMN "+builder.columnSql(c))
+		}
+	}
+	for _, c := range columns {
+		o, ok := oldColumnMap[c.name]
+		if !ok || o == c {
+			continue
+		}
+		switch builder.dialect {
+		case SQLite:
+			return nil, &BuildError{Op: "migrate", Field: c.name, Row: -1, Detail: "modify column", Err: ErrMigration}
+		case PostgreSQL:
+			column := table + " ALTER COLUMN " + builder.dialect.Quote(c.name)
+			if o.typ != c.typ {
+				list = append(list, column+" TYPE "+c.typ)
+			}
+			if o.notNull != c.notNull {
+				if c.notNull {
+					list = append(list, column+" SET NOT NULL")
+				} else {
+					list = append(list, column+" DROP NOT NULL")
+				}
+			}
+			if o.def != c.def {
+				if "" == c.def {
+					list = append(list, column+" DROP DEFAULT")
+				} else {
+					list = append(list, column+" SET DEFAULT "+c.def)
+				}
+			}
+		default:
+			list = append(list, table+" MODIFY COLUMN "+builder.columnSql(c))
+		}
+	}
+
+	for _, idx := range indexes {
+		if o, ok := oldIndexMap[idx.name]; !ok || !sameIndex(o, idx) {
+			list = append(list, builder.createIndexSql(idx))
+		}
+	}
+	return list, nil
+}
+
+// tableDef 解析结构体的列及索引定义
+func (builder *SqlBuilder) tableDef(model interface{}) ([]columnDef, []indexDef, error) {
+	b := *builder
+	b.Model = model
+	originType := b.modelType()
+	if nil == originType {
+		return nil, nil, &BuildError{Op: "ddl", Row: -1, Detail: fmt.Sprintf("%T", model), Err: ErrModelKind}
+	}
+	if err := b.validate(); nil != err {
+		return nil, nil, withOp(err, "ddl")
+	}
+	meta := getModelMeta(originType)
+	columns := make([]columnDef, 0, len(meta.fields))
+	indexes := make([]indexDef, 0)
+	indexMap := make(map[string]int)
+	addIndex := func(name string, unique bool, column string) {
+		if i, ok := indexMap[name]; ok {
+			indexes[i].columns = append(indexes[i].columns, column)
+			return
+		}
+		indexMap[name] = len(indexes)
+		indexes = append(indexes, indexDef{name: name, unique: unique, columns: []string{column}})
+	}
+	for _, f := range meta.fields {
+		for _, name := range []string{f.options["index"], f.options["unique"]} {
+			if "" != name && !validIdentifier(name) {
+				return nil, nil, buildErr("ddl", ErrInvalidIdentifier, name)
+			}
+		}
+		c := columnDef{name: f.column, notNull: f.typ.Kind() != reflect.Ptr, pk: f.has("pk"), auto: f.has("auto")}
+		size := defaultVarcharSize
+		if v, ok := f.option("size"); ok {
+			n, err := strconv.Atoi(v)
+			if nil != err {
+				return nil, nil, &BuildError{Op: "ddl", Field: f.name, Row: -1, Detail: "size=" + v, Err: err}
+			}
+			size = n
+		}
+		if v, ok := f.option("type"); ok {
+			c.typ = v
+		} else {
+			c.typ = builder.dialect.columnType(f.typ, size, c.auto)
+		}
+		if "" == c.typ {
+			return nil, nil, &BuildError{Op: "ddl", Field: f.name, Row: -1, Detail: f.typ.String(), Err: ErrColumnType}
+		}
+		if f.has("null") {
+			c.notNull = false
+		}
+		if f.has("notnull") || c.pk {
+			c.notNull = true
+		}
+		c.def, _ = f.option("default")
+		columns = append(columns, c)
+
+		if v, ok := f.option("index"); ok {
+			if "" == v {
+				v = "idx_" + builder.Table + "_" + c.name
+			}
+			addIndex(v, false, c.name)
+		}
+		if v, ok := f.option("unique"); ok {
+			addIndex(builder.uniqueName(v, c.name), true, c.name)
+		}
+	}
+	return columns, indexes, nil
+}
+
+// uniqueName 唯一索引名，unique未指定名称时为 uk_表名_列名
+func (builder *SqlBuilder) uniqueName(name, column string) string {
+	if "" == name {
+		return "uk_" + builder.Table + "_" + column
+	}
+	return name
+}
+
+// uniqueKeys 结构体的唯一键，按唯一索引名分组，与BuildCreateTable生成的唯一索引一致
+func (builder *SqlBuilder) uniqueKeys(t reflect.Type) []indexDef {
+	indexes := make([]indexDef, 0)
+	indexMap := make(map[string]int)
+	for _, f := range getModelMeta(t).fields {
+		v, ok := f.option("unique")
+		if !ok {
+			continue
+		}
+		name := builder.uniqueName(v, f.column)
+		if i, ok := indexMap[name]; ok {
+			indexes[i].columns = append(indexes[i].columns, f.column)
+			continue
+		}
+		indexMap[name] = len(indexes)
+		indexes = append(indexes, indexDef{name: name, unique: true, columns: []string{f.column}})
+	}
+	return indexes
+}
+
+// columnSql 列定义语句
+func (builder *SqlBuilder) columnSql(c columnDef) string {
+	sql := builder.dialect.Quote(c.name) + " " + c.typ
+	if c.auto && builder.dialect == SQLite {
+		// SQLite自增列必须为列级主键
+		return sql + " PRIMARY KEY AUTOINCREMENT"
+	}
+	if c.notNull {
+		sql += " NOT NULL"
+	}
+	if "" != c.def {
+		sql += " DEFAULT " + c.def
+	}
+	if c.auto && builder.dialect == MySQL {
+		sql += " AUTO_INCREMENT"
+	}
+	return sql
+}
+
+// createIndexSql 建索引语句
+func (builder *SqlBuilder) createIndexSql(idx indexDef) string {
+	columns := make([]string, 0, len(idx.columns))
+	for _, c := range idx.columns {
+		columns = append(columns, builder.dialect.Quote(c))
+	}
+	sql := "CREATE INDEX "
+	if idx.unique {
+		sql = "CREATE UNIQUE INDEX "
+	}
+	return sql + builder.dialect.Quote(idx.name) + " ON " + builder.dialect.Quote(builder.Table) +
+		" (" + strings.Join(columns, ",") + ")"
+}
+
+// sameIndex 索引定义是否相同
+func sameIndex(a, b indexDef) bool {
+	if a.unique != b.unique || len(a.columns) != len(b.columns) {
+		return false
+	}
+	for i := range a.columns {
+		if a.columns[i] != b.columns[i] {
+			return false
+		}
+	}
+	return true
+}
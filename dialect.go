@@ -0,0 +1,287 @@
+// Package pocket Create at 2026-10-18 10:40
+package pocket
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// identifierPattern 来自用户输入的列名，字母或下划线开头，可包含字母、数字、下划线及$，用于OrderBy、Sort、Pick
+var identifierPattern = regexp.MustCompile(`^[\p{L}_][\p{L}\p{N}_$]*$`)
+
+// Dialect sql方言，控制标识符引用、占位符、upsert语法及RETURNING支持
+type Dialect uint32
+
+const (
+	// MySQL 反引号引用标识符，?占位符
+	MySQL Dialect = iota
+	// PostgreSQL 双引号引用标识符，$n占位符
+	PostgreSQL
+	// SQLite 双引号引用标识符，?占位符
+	SQLite
+)
+
+// String dialect name
+func (d Dialect) String() string {
+	switch d {
+	case MySQL:
+		return "mysql"
+	case PostgreSQL:
+		return "postgres"
+	case SQLite:
+		return "sqlite"
+	default:
+		return "unknown"
+	}
+}
+
+// Quote 引用标识符，带表别名的列 o.id 分别引用，* 不引用，标识符中的引号加倍转义
+func (d Dialect) Quote(name string) string {
+	if "*" == name {
+		return name
+	}
+	if i := strings.LastIndex(name, "."); i > 0 {
+		return d.Quote(name[:i]) + "." + d.Quote(name[i+1:])
+	}
+	switch d {
+	case PostgreSQL, SQLite:
+		return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+	default:
+		return "`" + strings.Replace(name, "`", "``", -1) + "`"
+	}
+}
+
+// quoteIdent 校验并引用标识符
+func (d Dialect) quoteIdent(name string) (string, error) {
+	if !validIdentifier(name) {
+		return "", buildErr("", ErrInvalidIdentifier, name)
+	}
+	return d.Quote(name), nil
+}
+
+// validIdentifier 校验标识符，支持以.分隔的限定名，如 o.id、o.*；引号由Quote转义，
+// 各部分只需非空且不含控制字符及NUL，如 order-items 合法
+func validIdentifier(name string) bool {
+	if "*" == name {
+		return true
+	}
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		if "*" == p && i > 0 && i == len(parts)-1 {
+			continue
+		}
+		if "" == p || !utf8.ValidString(p) {
+			return false
+		}
+		for _, r := range p {
+			if unicode.IsControl(r) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Placeholder 第n个参数的占位符，n从1开始
+func (d Dialect) Placeholder(n int) string {
+	switch d {
+	case PostgreSQL:
+		return fmt.Sprintf("$%d", n)
+	default:
+		return "?"
+	}
+}
+
+// Rebind 将sql中的?占位符替换为方言的占位符，忽略引用标识符中的?
+func (d Dialect) Rebind(sql string) string {
+	if d.Placeholder(1) == "?" {
+		return sql
+	}
+	quote := d.Quote("")[0]
+	quoted := false
+	n := 0
+	var b strings.Builder
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == quote:
+			quoted = !quoted
+			b.WriteByte(c)
+		case c == '?' && !quoted:
+			n++
+			b.WriteString(d.Placeholder(n))
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// Returning 是否支持 RETURNING 子句，SQLite需3.35及以上版本
+func (d Dialect) Returning() bool {
+	switch d {
+	case PostgreSQL, SQLite:
+		return true
+	default:
+		return false
+	}
+}
+
+// LastInsertId 驱动是否支持sql.Result的LastInsertId，PostgreSQL需通过RETURNING获取
+func (d Dialect) LastInsertId() bool {
+	return d != PostgreSQL
+}
+
+// DefaultValue VALUES中是否支持DEFAULT关键字
+func (d Dialect) DefaultValue() bool {
+	return d != SQLite
+}
+
+// MaxParams 单条语句允许的最大参数个数
+func (d Dialect) MaxParams() int {
+	switch d {
+	case SQLite:
+		// SQLITE_MAX_VARIABLE_NUMBER，3.32.0及以上版本默认值
+		return 32766
+	default:
+		return 65535
+	}
+}
+
+// upsert 冲突时的更新子句，keys为唯一键列，columns为冲突时取插入值更新的列，
+// updated为冲突时以参数更新的列(如更新时间)，参数按updated的顺序排在插入的参数之后
+func (d Dialect) upsert(keys []string, columns []string, updated []string) string {
+	set := ""
+	for _, c := range columns {
+		if "" != set {
+			set += ","
+		}
+		switch d {
+		case PostgreSQL, SQLite:
+			set += d.Quote(c) + "=EXCLUDED." + d.Quote(c)
+		default:
+			set += d.Quote(c) + "=VALUES(" + d.Quote(c) + ")"
+		}
+	}
+	for _, c := range updated {
+		if "" != set {
+			set += ","
+		}
+		set += d.Quote(c) + "=?"
+	}
+	switch d {
+	case PostgreSQL, SQLite:
+		target := ""
+		for _, k := range keys {
+			if "" != target {
+				target += ","
+			}
+			target += d.Quote(k)
+		}
+		if "" == set {
+			return " ON CONFLICT (" + target + ") DO NOTHING"
+		}
+		return " ON CONFLICT (" + target + ") DO UPDATE SET " + set
+	default:
+		if "" == set {
+			// 无更新列时保持原值，等价于忽略冲突
+			return " ON DUPLICATE KEY UPDATE " + d.Quote(keys[0]) + "=" + d.Quote(keys[0])
+		}
+		return " ON DUPLICATE KEY UPDATE " + set
+	}
+}
+
+// columnType go类型对应的列类型，size为字符串长度，auto为自增列
+func (d Dialect) columnType(t reflect.Type, size int, auto bool) string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		switch d {
+		case PostgreSQL:
+			return "TIMESTAMP"
+		default:
+			return "DATETIME"
+		}
+	}
+	if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+		switch d {
+		case PostgreSQL:
+			return "BYTEA"
+		default:
+			return "BLOB"
+		}
+	}
+	if d == SQLite {
+		switch t.Kind() {
+		case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return "INTEGER"
+		case reflect.Float32, reflect.Float64:
+			return "REAL"
+		case reflect.String:
+			return "TEXT"
+		default:
+			return ""
+		}
+	}
+	if d == PostgreSQL {
+		switch t.Kind() {
+		case reflect.Bool:
+			return "BOOLEAN"
+		case reflect.Int8, reflect.Int16, reflect.Uint8:
+			return "SMALLINT"
+		case reflect.Int, reflect.Int32, reflect.Uint16:
+			if auto {
+				return "SERIAL"
+			}
+			return "INTEGER"
+		case reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+			if auto {
+				return "BIGSERIAL"
+			}
+			return "BIGINT"
+		case reflect.Float32:
+			return "REAL"
+		case reflect.Float64:
+			return "DOUBLE PRECISION"
+		case reflect.String:
+			return fmt.Sprintf("VARCHAR(%d)", size)
+		default:
+			return ""
+		}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "TINYINT(1)"
+	case reflect.Int8:
+		return "TINYINT"
+	case reflect.Uint8:
+		return "TINYINT UNSIGNED"
+	case reflect.Int16:
+		return "SMALLINT"
+	case reflect.Uint16:
+		return "SMALLINT UNSIGNED"
+	case reflect.Int, reflect.Int32:
+		return "INT"
+	case reflect.Uint32:
+		return "INT UNSIGNED"
+	case reflect.Int64:
+		return "BIGINT"
+	case reflect.Uint, reflect.Uint64:
+		return "BIGINT UNSIGNED"
+	case reflect.Float32:
+		return "FLOAT"
+	case reflect.Float64:
+		return "DOUBLE"
+	case reflect.String:
+		return fmt.Sprintf("VARCHAR(%d)", size)
+	default:
+		return ""
+	}
+}
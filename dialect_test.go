@@ -0,0 +1,161 @@
+package pocket
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDialectQuote(t *testing.T) {
+	cases := []struct {
+		dialect Dialect
+		name    string
+		want    string
+	}{
+		{MySQL, "user", "`user`"},
+		{MySQL, "u.id", "`u`.`id`"},
+		{MySQL, "u.*", "`u`.*"},
+		{MySQL, "*", "*"},
+		{MySQL, "a`b", "`a``b`"},
+		{PostgreSQL, "user", `"user"`},
+		{PostgreSQL, "u.id", `"u"."id"`},
+		{PostgreSQL, `a"b`, `"a""b"`},
+		{SQLite, "user", `"user"`},
+		{SQLite, "u.id", `"u"."id"`},
+		{SQLite, `a"b`, `"a""b"`},
+	}
+	for _, c := range cases {
+		if got := c.dialect.Quote(c.name); got != c.want {
+			t.Errorf("%s Quote(%q) = %s, want %s", c.dialect, c.name, got, c.want)
+		}
+	}
+}
+
+func TestDialectRebind(t *testing.T) {
+	sql := `SELECT "a?b",c FROM t WHERE x=? AND y IN (?,?)`
+	cases := []struct {
+		dialect Dialect
+		want    string
+	}{
+		{MySQL, sql},
+		{SQLite, sql},
+		{PostgreSQL, `SELECT "a?b",c FROM t WHERE x=$1 AND y IN ($2,$3)`},
+	}
+	for _, c := range cases {
+		if got := c.dialect.Rebind(sql); got != c.want {
+			t.Errorf("%s Rebind = %s, want %s", c.dialect, got, c.want)
+		}
+	}
+	if got := PostgreSQL.Rebind(`SELECT "a""?" FROM t WHERE x=?`); got != `SELECT "a""?" FROM t WHERE x=$1` {
+		t.Errorf("postgres Rebind escaped quote = %s", got)
+	}
+}
+
+func TestDialectUpsert(t *testing.T) {
+	cases := []struct {
+		dialect Dialect
+		columns []string
+		want    string
+	}{
+		{MySQL, []string{"name", "age"}, " ON DUPLICATE KEY UPDATE `name`=VALUES(`name`),`age`=VALUES(`age`)"},
+		{MySQL, nil, " ON DUPLICATE KEY UPDATE `id`=`id`"},
+		{PostgreSQL, []string{"name", "age"}, ` ON CONFLICT ("id") DO UPDATE SET "name"=EXCLUDED."name","age"=EXCLUDED."age"`},
+		{PostgreSQL, nil, ` ON CONFLICT ("id") DO NOTHING`},
+		{SQLite, []string{"name"}, ` ON CONFLICT ("id") DO UPDATE SET "name"=EXCLUDED."name"`},
+		{SQLite, nil, ` ON CONFLICT ("id") DO NOTHING`},
+	}
+	for _, c := range cases {
+		if got := c.dialect.upsert([]string{"id"}, c.columns, nil); got != c.want {
+			t.Errorf("%s upsert(%v) = %s, want %s", c.dialect, c.columns, got, c.want)
+		}
+	}
+}
+
+type dialectUser struct {
+	ID   *int64  `db:"id,pk"`
+	Name *string `db:"name,set"`
+}
+
+func TestDialectStatements(t *testing.T) {
+	id, name := int64(1), "n"
+	u := &dialectUser{ID: &id, Name: &name}
+	cases := []struct {
+		dialect Dialect
+		insert  string
+		upsert  string
+		update  string
+	}{
+		{
+			MySQL,
+			"INSERT INTO `user`(`id`,`name`) VALUES (?,?)",
+			"INSERT INTO `user`(`id`,`name`) VALUES (?,?) ON DUPLICATE KEY UPDATE `name`=VALUES(`name`)",
+			"UPDATE `user` SET `name`=? WHERE `id`=?",
+		},
+		{
+			PostgreSQL,
+			`INSERT INTO "user"("id","name") VALUES ($1,$2) RETURNING "id"`,
+			`INSERT INTO "user"("id","name") VALUES ($1,$2) ON CONFLICT ("id") DO UPDATE SET "name"=EXCLUDED."name" RETURNING "id"`,
+			`UPDATE "user" SET "name"=$1 WHERE "id"=$2 RETURNING "id"`,
+		},
+		{
+			SQLite,
+			`INSERT INTO "user"("id","name") VALUES (?,?) RETURNING "id"`,
+			`INSERT INTO "user"("id","name") VALUES (?,?) ON CONFLICT ("id") DO UPDATE SET "name"=EXCLUDED."name" RETURNING "id"`,
+			`UPDATE "user" SET "name"=? WHERE "id"=? RETURNING "id"`,
+		},
+	}
+	for _, c := range cases {
+		builder := func() *SqlBuilder {
+			b := NewSqlBuilder("user", u, c.dialect)
+			if c.dialect.Returning() {
+				b.Returning("id")
+			}
+			return b
+		}
+		if sql, _, err := builder().BuildInsertRow(); nil != err || sql != c.insert {
+			t.Errorf("%s insert = %s, %v, want %s", c.dialect, sql, err, c.insert)
+		}
+		if sql, _, err := builder().BuildUpsert(); nil != err || sql != c.upsert {
+			t.Errorf("%s upsert = %s, %v, want %s", c.dialect, sql, err, c.upsert)
+		}
+		if sql, _, err := builder().Condition(Eq("id", 1)).BuildUpdate(); nil != err || sql != c.update {
+			t.Errorf("%s update = %s, %v, want %s", c.dialect, sql, err, c.update)
+		}
+	}
+	if _, _, err := NewSqlBuilder("user", u, MySQL).Returning("id").BuildInsertRow(); !errors.Is(err, ErrReturning) {
+		t.Errorf("mysql returning err = %v, want ErrReturning", err)
+	}
+}
+
+type identItem struct {
+	ID   *int64 `db:"id"`
+	Item *int64 `db:"line-item"`
+}
+
+func TestIdentifiers(t *testing.T) {
+	id := int64(1)
+	sql, _, err := NewSqlBuilder("order-items", &identItem{ID: &id}, PostgreSQL).BuildInsertRow()
+	if nil != err || sql != `INSERT INTO "order-items"("id") VALUES ($1)` {
+		t.Errorf("insert into order-items = %s, %v", sql, err)
+	}
+	sql, _, err = NewSqlBuilder("o`x", &identItem{ID: &id}).BuildInsertRow()
+	if nil != err || sql != "INSERT INTO `o``x`(`id`) VALUES (?)" {
+		t.Errorf("insert with backtick = %s, %v", sql, err)
+	}
+	for _, table := range []string{"", "a\x00b", "a\nb", "a.", "\xff"} {
+		if _, _, err = NewSqlBuilder(table, &identItem{ID: &id}).BuildInsertRow(); !errors.Is(err, ErrInvalidIdentifier) {
+			t.Errorf("table %q err = %v, want ErrInvalidIdentifier", table, err)
+		}
+	}
+	if _, _, err = NewSqlBuilder("t", &identItem{}).OrderBy("line-item", false).BuildSelect(); !errors.Is(err, ErrInvalidIdentifier) {
+		t.Errorf("OrderBy line-item err = %v, want ErrInvalidIdentifier", err)
+	}
+	if _, _, err = NewSqlBuilder("t", &identItem{}).Pick("id", "id;drop").BuildSelect(); !errors.Is(err, ErrInvalidIdentifier) {
+		t.Errorf("Pick err = %v, want ErrInvalidIdentifier", err)
+	}
+	if _, _, err = NewSqlBuilder("t", &identItem{}).Sort("-name").BuildSelect(); !errors.Is(err, ErrUnknownColumn) {
+		t.Errorf("Sort unknown err = %v, want ErrUnknownColumn", err)
+	}
+	if sql, _, err = NewSqlBuilder("t", &identItem{}).Sort("-id").BuildSelect(); nil != err || sql != "SELECT `id`,`line-item` FROM `t` ORDER BY `id` DESC" {
+		t.Errorf("Sort = %s, %v", sql, err)
+	}
+}
@@ -0,0 +1,103 @@
+// Package pocket Create at 2026-10-18 14:20
+package pocket
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SqlBuilder及Executor返回的错误，可用errors.Is判断，需要出错的字段或行时用errors.As取*BuildError
+var (
+	// ErrModelKind Model或dest的类型不符合要求
+	ErrModelKind = errors.New("pocket: wrong model kind")
+	// ErrEmptySlice 批量操作的Model为空切片
+	ErrEmptySlice = errors.New("pocket: empty slice")
+	// ErrNoColumns Model中没有可写入或查询的列
+	ErrNoColumns = errors.New("pocket: no columns")
+	// ErrInconsistentColumns 批量插入时某行的列与第一行不一致
+	ErrInconsistentColumns = errors.New("pocket: inconsistent columns")
+	// ErrDefaultValue 方言不支持在VALUES中使用DEFAULT
+	ErrDefaultValue = errors.New("pocket: DEFAULT in VALUES not supported by dialect")
+	// ErrNoCondition 缺少条件，禁止全表操作
+	ErrNoCondition = errors.New("pocket: no condition, full table operation not allowed")
+	// ErrCondition 条件参数或操作符错误
+	ErrCondition = errors.New("pocket: invalid condition")
+	// ErrInvalidIdentifier 表名、列名等标识符非法
+	ErrInvalidIdentifier = errors.New("pocket: invalid identifier")
+	// ErrUnknownColumn 列不在Model中
+	ErrUnknownColumn = errors.New("pocket: unknown column")
+	// ErrCursor 分页游标与排序列数量不一致
+	ErrCursor = errors.New("pocket: cursor values do not match sort columns")
+	// ErrSoftDelete 不支持的软删除字段类型
+	ErrSoftDelete = errors.New("pocket: unsupported soft delete field type")
+	// ErrReturning 当前方言不支持RETURNING
+	ErrReturning = errors.New("pocket: RETURNING not supported by dialect")
+	// ErrNoUniqueKey upsert缺少唯一键，需在tag中标记pk或unique
+	ErrNoUniqueKey = errors.New("pocket: no pk or unique key")
+	// ErrAmbiguousKey Model有多个唯一键，upsert需用OnConflict指定冲突键
+	ErrAmbiguousKey = errors.New("pocket: multiple unique keys, choose one with OnConflict")
+	// ErrTooManyParams 单行参数个数超过限制
+	ErrTooManyParams = errors.New("pocket: too many params in one row")
+	// ErrColumnType 无法推断列类型，需在tag中指定type
+	ErrColumnType = errors.New("pocket: unknown column type")
+	// ErrMigration 方言不支持的表结构变更
+	ErrMigration = errors.New("pocket: migration not supported by dialect")
+	// ErrOptimisticLock 带version列的更新未影响任何行，记录已被修改或不存在
+	ErrOptimisticLock = errors.New("pocket: optimistic lock conflict")
+)
+
+// ErrStopImport ImportEach、ImportBatch、ImportCsvEach的回调返回该错误(或包装了它的错误)时停止导入，不作为错误返回
+var ErrStopImport = errors.New("pocket: stop import")
+
+// BuildError 生成sql失败的详细信息，Err为上面的哨兵错误
+type BuildError struct {
+	// Op 出错的操作，如insert、update、select、where
+	Op string
+	// Field 出错的字段或列，无时为空
+	Field string
+	// Row 出错的行，从0开始，与行无关时为-1
+	Row int
+	// Detail 补充说明，如实际的类型或操作符
+	Detail string
+	Err    error
+}
+
+func (e *BuildError) Error() string {
+	msg := e.Err.Error()
+	if "" != e.Op {
+		msg = e.Op + ": " + msg
+	}
+	if e.Row >= 0 {
+		msg += fmt.Sprintf(", row %d", e.Row)
+	}
+	if "" != e.Field {
+		msg += fmt.Sprintf(", field %q", e.Field)
+	}
+	if "" != e.Detail {
+		msg += ", " + e.Detail
+	}
+	return msg
+}
+
+func (e *BuildError) Unwrap() error {
+	return e.Err
+}
+
+// buildErr 与行无关的错误
+func buildErr(op string, err error, field string) *BuildError {
+	return &BuildError{Op: op, Field: field, Row: -1, Err: err}
+}
+
+// rowErr 批量操作中第row行的错误
+func rowErr(op string, err error, row int, field string) *BuildError {
+	return &BuildError{Op: op, Field: field, Row: row, Err: err}
+}
+
+// withOp 补充未设置Op的BuildError
+func withOp(err error, op string) error {
+	var e *BuildError
+	if errors.As(err, &e) && "" == e.Op {
+		e.Op = op
+	}
+	return err
+}
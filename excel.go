@@ -8,12 +8,13 @@ import (
 	"io"
 	"net/url"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	excelize "github.com/360EntSecGroup-Skylar/excelize/v2"
-) 
+)
 
 const (
 	headerStyle = `{
@@ -40,7 +41,6 @@ const (
                            "wrap_text":true
                            }
               }`
-	a = 97
 )
 
 // Formatter data formatter
@@ -83,13 +83,31 @@ type timeImportFormatter struct {
 	timeLayout string
 }
 
-func (t timeImportFormatter) format(value interface{}) string {
-	m, err := time.Parse(t.timeLayout, fmt.Sprintf("%v", value))
+func (t timeImportFormatter) parse(value string) (string, error) {
+	m, err := time.Parse(t.timeLayout, value)
 	if nil != err {
-		DefaultLogger.Error(err.Error())
-		return "0"
+		return "", fmt.Errorf("日期格式错误，应为 %s", t.timeLayout)
 	}
-	return fmt.Sprintf("%d", m.Unix())
+	return fmt.Sprintf("%d", m.Unix()), nil
+}
+
+// importFormat 导入时将单元格的值转换为字段的值
+type importFormat interface {
+	parse(value string) (string, error)
+}
+
+// parse 导入时按名称查找枚举值
+func (e enumFormatter) parse(value string) (string, error) {
+	v, ok := e.enum[value]
+	if !ok {
+		labels := make([]string, 0, len(e.enum))
+		for k := range e.enum {
+			labels = append(labels, k)
+		}
+		sort.Strings(labels)
+		return "", fmt.Errorf("未知的选项 %q，可选：%s", value, strings.Join(labels, ","))
+	}
+	return v, nil
 }
 
 // Sheet 表
@@ -98,6 +116,7 @@ type Sheet struct {
 	T            reflect.Type      `json:"-"`                 // 列的类型
 	Result       *[]interface{}    `json:"result,omitempty"`  // 导入结果
 	Content      []interface{}     `json:"content,omitempty"` // 导出数据
+	Source       RowSource         `json:"-"`                 // 导出数据来源，设置时代替Content逐行读取，如Cursor
 	HeaderStyle  string            `json:"-"`                 // 头部单元格样式
 	ContentStyle string            `json:"-"`                 // 内容单元格样式
 	Panes        []string          `json:"-"`
@@ -113,9 +132,139 @@ type Column struct {
 	Style        string  // 单元格样式，覆盖Sheet的ContentStyle
 }
 
-type field struct {
-	Idx int
-	Tag string
+// excelColumn 带excel_column tag的字段，tag格式 `excel_column:"标题,index=1"`，
+// index为从1开始的列号，未指定index的字段按字段顺序依次排在空闲的列
+type excelColumn struct {
+	field  int       // 字段在结构体中的下标
+	title  string    // 表头
+	number int       // 列号，从1开始
+	cell   string    // 列名，如 A、AA
+	format Formatter // excel_formatter
+	rules  []validateRule
+}
+
+// excelColumns 解析结构体(指针)类型的excel列，按列号排序
+func excelColumns(t reflect.Type) ([]excelColumn, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, errors.New("不支持的类型，只能是指针或结构体")
+	}
+	list := make([]excelColumn, 0)
+	used := make(map[int]bool)
+	for j := 0; j < t.NumField(); j++ {
+		tag := t.Field(j).Tag.Get("excel_column")
+		if "" == tag {
+			continue
+		}
+		c := excelColumn{field: j, title: tag}
+		if i := strings.LastIndex(tag, ",index="); i >= 0 {
+			n, err := strconv.Atoi(tag[i+len(",index="):])
+			if nil != err || n < 1 {
+				return nil, fmt.Errorf("%s: 非法的列号 %q", tag[:i], tag[i+1:])
+			}
+			if used[n] {
+				return nil, fmt.Errorf("%s: 列号 %d 重复", tag[:i], n)
+			}
+			used[n] = true
+			c.title = tag[:i]
+			c.number = n
+		}
+		form := t.Field(j).Tag.Get("excel_formatter")
+		if "" != form {
+			v, err := url.ParseQuery(form)
+			if nil != err {
+				return nil, err
+			}
+			if err = DecodeQuery(&c.format, v); nil != err {
+				return nil, err
+			}
+		}
+		rules, err := parseValidate(t.Field(j).Tag.Get("validate"))
+		if nil != err {
+			return nil, fmt.Errorf("%s: %s", c.title, err.Error())
+		}
+		c.rules = rules
+		list = append(list, c)
+	}
+	next := 1
+	for i := range list {
+		if 0 == list[i].number {
+			for used[next] {
+				next++
+			}
+			list[i].number = next
+			used[next] = true
+		}
+		cell, err := excelize.ColumnNumberToName(list[i].number)
+		if nil != err {
+			return nil, err
+		}
+		list[i].cell = cell
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].number < list[j].number
+	})
+	return list, nil
+}
+
+// enum 解析枚举，格式 值:名称,值:名称，返回值及名称列表
+func (f Formatter) enum() ([]string, []string) {
+	keys := make([]string, 0)
+	labels := make([]string, 0)
+	for _, item := range strings.Split(f.Enum, ",") {
+		i := strings.Index(item, ":")
+		if i < 0 {
+			continue
+		}
+		keys = append(keys, item[:i])
+		labels = append(labels, item[i+1:])
+	}
+	return keys, labels
+}
+
+// exportFormatter 导出时的formatter，没有时返回nil
+func (f Formatter) exportFormatter() Format {
+	if "" != f.Time {
+		return timeExportFormatter{timeLayout: f.Time}
+	}
+	if "" != f.Enum {
+		enum := enumFormatter{enum: make(map[string]string, 0)}
+		keys, labels := f.enum()
+		for i := range keys {
+			enum.enum[keys[i]] = labels[i]
+		}
+		return enum
+	}
+	return nil
+}
+
+// importFormatter 导入时的formatter，没有时返回nil
+func (f Formatter) importFormatter() importFormat {
+	if "" != f.Time {
+		return timeImportFormatter{timeLayout: f.Time}
+	}
+	if "" != f.Enum {
+		enum := enumFormatter{enum: make(map[string]string, 0)}
+		keys, labels := f.enum()
+		for i := range keys {
+			enum.enum[labels[i]] = keys[i]
+		}
+		return enum
+	}
+	return nil
+}
+
+// cellValue 导出的单元格的值，指针字段取其指向的值，nil时为空
+func cellValue(v reflect.Value) interface{} {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	return v.Interface()
 }
 
 type mergeItem struct {
@@ -131,294 +280,230 @@ func Export(sheets []Sheet) (*bytes.Buffer, error) {
 	xlsx := excelize.NewFile()
 	last := 0
 	for _, s := range sheets {
-		column := make(map[string]Column, 0)
-		formatter := make(map[string]Format, 0)
 		last = xlsx.NewSheet(s.Name)
-		t := s.T
-		if nil == t {
-			if len(s.Content) == 0 {
-				DefaultLogger.Error("无法识别类型")
-				return nil, errors.New("无法识别类型")
-			}
-			t = reflect.TypeOf(s.Content[0])
+		if err := exportSheet(xlsx, s, false); nil != err {
+			return nil, err
 		}
-		if t.Kind() != reflect.Ptr && t.Kind() != reflect.Struct {
-			DefaultLogger.Error("不支持的类型，只能是指针或结构体")
-			return nil, errors.New("不支持的类型，只能是指针或结构体")
+	}
+
+	xlsx.SetActiveSheet(last)
+	return xlsx.WriteToBuffer()
+}
+
+// ExportStream 流式导出到w，逐行读取Sheet.Source(或Content)并通过StreamWriter写入，不在内存中保留全部单元格；
+// 表头样式、列宽、formatter、合并及下拉列表与Export一致，Source可为Cursor或ChanSource
+func ExportStream(w io.Writer, sheets []Sheet) error {
+	xlsx := excelize.NewFile()
+	last := 0
+	for _, s := range sheets {
+		last = xlsx.NewSheet(s.Name)
+	}
+	// SetActiveSheet会重新读取各sheet，需在写入前调用
+	xlsx.SetActiveSheet(last)
+	for _, s := range sheets {
+		if err := exportSheet(xlsx, s, true); nil != err {
+			return err
 		}
-		size := len(s.Content)
-		switch t.Kind() {
-		case reflect.Ptr:
-			style, err := xlsx.NewStyle(s.HeaderStyle)
-			if nil != err {
-				DefaultLogger.Warn("创建表头样式失败")
+	}
+
+	if err := xlsx.Write(w); nil != err {
+		DefaultLogger.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// exportSource 导出的数据来源及列，未设置Sheet.T时读取第一行识别类型，first为已读取的第一行
+func exportSource(s Sheet) (source RowSource, first interface{}, list []excelColumn, err error) {
+	source = s.Source
+	if nil == source {
+		source = &sliceSource{rows: s.Content}
+	}
+	t := s.T
+	if nil == t {
+		if !source.Next() {
+			if err = source.Err(); nil != err {
+				return nil, nil, nil, err
 			}
-			for j := 0; j < t.Elem().NumField(); j++ {
-				tag := t.Elem().Field(j).Tag.Get("excel_column")
-				if "" != tag {
-					if nil == err {
-						xlsx.SetCellStyle(s.Name, fmt.Sprintf("%s1", string(a+j)),
-							fmt.Sprintf("%s1", string(a+j)), style)
-					}
-					title := tag
-					if nil != s.Columns {
-						if c, ok := s.Columns[tag]; ok {
-							column[tag] = Column{
-								cell:         string(a + j),
-								Width:        c.Width,
-								Merge:        c.Merge,
-								MergeExclude: c.MergeExclude,
-								Style:        c.Style,
-							}
-							xlsx.SetColWidth(s.Name, fmt.Sprintf("%s", string(a+j)),
-								fmt.Sprintf("%s", string(a+j)), c.Width)
-						} else {
-							column[tag] = Column{cell: string(a + j)}
-						}
-					} else {
-						column[tag] = Column{cell: string(a + j)}
-					}
+			DefaultLogger.Error("无法识别类型")
+			return nil, nil, nil, errors.New("无法识别类型")
+		}
+		first = source.Value()
+		t = reflect.TypeOf(first)
+	}
+	if t.Kind() != reflect.Ptr && t.Kind() != reflect.Struct {
+		DefaultLogger.Error("不支持的类型，只能是指针或结构体")
+		return nil, nil, nil, errors.New("不支持的类型，只能是指针或结构体")
+	}
+	if list, err = excelColumns(t); nil != err {
+		DefaultLogger.Error(err.Error())
+		return nil, nil, nil, err
+	}
+	return source, first, list, nil
+}
 
-					xlsx.SetCellValue(s.Name, fmt.Sprintf("%s1", string(a+j)), title)
+// exportSheet 写入一个sheet，stream为true时使用StreamWriter按行写入
+func exportSheet(xlsx *excelize.File, s Sheet, stream bool) error {
+	source, first, list, err := exportSource(s)
+	if nil != err {
+		return err
+	}
 
-					form := t.Elem().Field(j).Tag.Get("excel_formatter")
-					if "" != form {
-						var f Formatter
-						v, err := url.ParseQuery(form)
-						if nil != err {
-							DefaultLogger.Error(err.Error())
-							return nil, err
-						}
-						err = DecodeQuery(&f, v)
-						if nil != err {
-							DefaultLogger.Error(err.Error())
-							return nil, err
-						}
-						if "" != f.Enum {
-							list := strings.Split(f.Enum, ",")
-							items := make([]string, 0)
-							if len(list) > 0 {
-								enum := enumFormatter{enum: make(map[string]string, 0)}
-								for i := 0; i < len(list); i++ {
-									enum.enum[list[i][:strings.Index(list[i], ":")]] = list[i][strings.Index(list[i], ":")+1:]
-									items = append(items, list[i][strings.Index(list[i], ":")+1:])
-								}
-								formatter[tag] = enum
-							}
-							if size > 0 {
-								dvRange := excelize.NewDataValidation(true)
-								dvRange.Sqref = fmt.Sprintf("%s2:%s%d", string(a+j), string(a+j), size+1)
-								err = dvRange.SetDropList(items)
-								if nil != err {
-									DefaultLogger.Error(err.Error())
-									//return nil, err
-								}
-								err = xlsx.AddDataValidation(s.Name, dvRange)
-								if nil != err {
-									DefaultLogger.Error(err.Error())
-									//return nil, err
-								}
-							}
-						}
-						if "" != f.Time {
-							formatter[tag] = timeExportFormatter{timeLayout: f.Time}
-						}
-					}
-				}
+	headerStyle, err := xlsx.NewStyle(s.HeaderStyle)
+	if nil != err {
+		DefaultLogger.Warn("创建表头样式失败")
+	}
+	contentStyle, err := xlsx.NewStyle(s.ContentStyle)
+	if nil != err {
+		DefaultLogger.Warn("创建表内容样式失败")
+	}
+	column := make(map[string]Column, 0)
+	formatter := make(map[string]Format, 0)
+	validations := make(map[string][]string, 0)
+	styles := make(map[string]int, 0)
+	width := 0
+	for _, ec := range list {
+		c := Column{cell: ec.cell}
+		if setting, ok := s.Columns[ec.title]; ok {
+			c.Width = setting.Width
+			c.Merge = setting.Merge
+			c.MergeExclude = setting.MergeExclude
+			c.Style = setting.Style
+			xlsx.SetColWidth(s.Name, ec.cell, ec.cell, c.Width)
+		}
+		column[ec.title] = c
+		styles[ec.title] = contentStyle
+		if len(c.Style) != 0 {
+			if columnStyle, err := xlsx.NewStyle(c.Style); nil == err {
+				styles[ec.title] = columnStyle
 			}
-		case reflect.Struct:
-			style, err := xlsx.NewStyle(s.HeaderStyle)
+		}
+		if f := ec.format.exportFormatter(); nil != f {
+			formatter[ec.title] = f
+		}
+		if "" != ec.format.Enum {
+			_, validations[ec.cell] = ec.format.enum()
+		}
+		if ec.number > width {
+			width = ec.number
+		}
+	}
+	for _, p := range s.Panes {
+		xlsx.SetPanes(s.Name, p)
+	}
+
+	// 列宽及冻结窗格需在创建StreamWriter前设置
+	var sw *excelize.StreamWriter
+	if stream {
+		if sw, err = xlsx.NewStreamWriter(s.Name); nil != err {
+			DefaultLogger.Error(err.Error())
+			return err
+		}
+	}
+	setRow := func(row int, cells []interface{}) error {
+		if nil != sw {
+			return sw.SetRow(fmt.Sprintf("A%d", row), cells)
+		}
+		for i, v := range cells {
+			cell, ok := v.(excelize.Cell)
+			if !ok {
+				continue
+			}
+			axis, err := excelize.CoordinatesToCellName(i+1, row)
 			if nil != err {
-				DefaultLogger.Warn("创建表头样式失败")
+				return err
 			}
-			for j := 0; j < t.NumField(); j++ {
-				tag := t.Field(j).Tag.Get("excel_column")
-				if "" != tag {
-					if nil == err {
-						xlsx.SetCellStyle(s.Name, fmt.Sprintf("%s1", string(97+j)),
-							fmt.Sprintf("%s1", string(97+j)), style)
-					}
-					title := tag
-					if nil != s.Columns {
-						if c, ok := s.Columns[tag]; ok {
-							column[tag] = Column{
-								cell:         string(a + j),
-								Width:        c.Width,
-								Merge:        c.Merge,
-								MergeExclude: c.MergeExclude,
-								Style:        c.Style,
-							}
-							xlsx.SetColWidth(s.Name, fmt.Sprintf("%s", string(a+j)),
-								fmt.Sprintf("%s", string(a+j)), c.Width)
-						} else {
-							column[tag] = Column{cell: string(a + j)}
-						}
-					} else {
-						column[tag] = Column{cell: string(a + j)}
-					}
-
-					xlsx.SetCellValue(s.Name, fmt.Sprintf("%s1", string(a+j)), title)
-					form := t.Field(j).Tag.Get("excel_formatter")
-					if "" != form {
-						var f Formatter
-						v, err := url.ParseQuery(form)
-						if nil != err {
-							DefaultLogger.Error(err.Error())
-							return nil, err
-						}
-						err = DecodeQuery(&f, v)
-						if nil != err {
-							DefaultLogger.Error(err.Error())
-							return nil, err
-						}
-						if "" != f.Enum {
-							list := strings.Split(f.Enum, ",")
-							items := make([]string, 0)
-							if len(list) > 0 {
-								enum := enumFormatter{enum: make(map[string]string, 0)}
-								for i := 0; i < len(list); i++ {
-									enum.enum[list[i][:strings.Index(list[i], ":")]] = list[i][strings.Index(list[i], ":")+1:]
-									items = append(items, list[i][strings.Index(list[i], ":")+1:])
-								}
-								formatter[tag] = enum
-							}
-							if size > 0 {
-								dvRange := excelize.NewDataValidation(true)
-								dvRange.Sqref = fmt.Sprintf("%s2:%s%d", string(a+j), string(a+j), size+1)
-								err = dvRange.SetDropList(items)
-								if nil != err {
-									DefaultLogger.Error(err.Error())
-									//return nil, err
-								}
-								err = xlsx.AddDataValidation(s.Name, dvRange)
-								if nil != err {
-									DefaultLogger.Error(err.Error())
-									//return nil, err
-								}
-							}
-						}
-						if "" != f.Time {
-							formatter[tag] = timeExportFormatter{timeLayout: f.Time}
-						}
-					}
-				}
+			if cell.StyleID > 0 {
+				xlsx.SetCellStyle(s.Name, axis, axis, cell.StyleID)
 			}
+			xlsx.SetCellValue(s.Name, axis, cell.Value)
 		}
-		if size == 0 {
-			continue
+		return nil
+	}
+
+	cells := make([]interface{}, width)
+	for _, ec := range list {
+		cells[ec.number-1] = excelize.Cell{StyleID: headerStyle, Value: ec.title}
+	}
+	if err = setRow(1, cells); nil != err {
+		DefaultLogger.Error(err.Error())
+		return err
+	}
+
+	merge := make(map[string]mergeItem)
+	size := 0
+	for ; ; size++ {
+		index := size
+		r := first
+		if index > 0 || nil == first {
+			if !source.Next() {
+				break
+			}
+			r = source.Value()
 		}
-		style, err := xlsx.NewStyle(s.ContentStyle)
-		if nil != err {
-			DefaultLogger.Warn("创建表内容样式失败")
-		}
-		merge := make(map[string]mergeItem)
-		for index, r := range s.Content {
-			switch t.Kind() {
-			case reflect.Ptr:
-				for j := 0; j < t.Elem().NumField(); j++ {
-					tag := t.Elem().Field(j).Tag.Get("excel_column")
-					if c, ok := column[tag]; ok {
-						if nil == err {
-							xlsx.SetCellStyle(s.Name, fmt.Sprintf("%s%d", c.cell, index+2),
-								fmt.Sprintf("%s%d", c.cell, index+2), style)
-						}
-						if len(c.Style) != 0 {
-							columnStyle, err := xlsx.NewStyle(c.Style)
-							if nil == err {
-								xlsx.SetCellStyle(s.Name, fmt.Sprintf("%s%d", c.cell, index+2),
-									fmt.Sprintf("%s%d", c.cell, index+2), columnStyle)
+		row := reflect.Indirect(reflect.ValueOf(r))
+		cells := make([]interface{}, width)
+		for _, ec := range list {
+			c := column[ec.title]
+			val := cellValue(row.Field(ec.field))
+			if f, ok := formatter[ec.title]; ok {
+				val = f.format(val)
+			}
+			cells[ec.number-1] = excelize.Cell{StyleID: styles[ec.title], Value: val}
+			if c.Merge {
+				if m, ok := merge[ec.title]; ok {
+					v := fmt.Sprintf("%v", val)
+					if v != m.Val {
+						if index-m.Start > -1 && "" != m.Val {
+							// 合并列
+							if len(m.Exclude) == 0 || !strings.HasPrefix(m.Val, m.Exclude) {
+								xlsx.MergeCell(s.Name, fmt.Sprintf("%s%d", m.Col, m.Start),
+									fmt.Sprintf("%s%d", m.Col, index+1))
 							}
 						}
-						val := reflect.ValueOf(r).Elem().Field(j).Interface()
-						if f, ok := formatter[tag]; ok {
-							val = f.format(reflect.ValueOf(r).Elem().Field(j).Interface())
-						}
-						xlsx.SetCellValue(s.Name, fmt.Sprintf("%s%d", c.cell, index+2), val)
-						if c.Merge {
-							if m, ok := merge[tag]; ok {
-								v := fmt.Sprintf("%v", val)
-								if v != m.Val {
-									if index-m.Start > -1 && "" != m.Val {
-										// 合并列
-										if len(m.Exclude) == 0 || !strings.HasPrefix(m.Val, m.Exclude) {
-											xlsx.MergeCell(s.Name, fmt.Sprintf("%s%d", m.Col, m.Start),
-												fmt.Sprintf("%s%d", m.Col, index+1))
-										}
-									}
-									merge[tag] = mergeItem{
-										Col:     c.cell,
-										Start:   index + 2,
-										End:     0,
-										Val:     fmt.Sprintf("%v", val),
-										Exclude: c.MergeExclude,
-									}
-								}
-							} else {
-								merge[tag] = mergeItem{
-									Col:     c.cell,
-									Start:   index + 2,
-									End:     0,
-									Val:     fmt.Sprintf("%v", val),
-									Exclude: c.MergeExclude,
-								}
-							}
+						merge[ec.title] = mergeItem{
+							Col:     c.cell,
+							Start:   index + 2,
+							End:     0,
+							Val:     fmt.Sprintf("%v", val),
+							Exclude: c.MergeExclude,
 						}
 					}
-				}
-			case reflect.Struct:
-				for j := 0; j < t.NumField(); j++ {
-					tag := t.Field(j).Tag.Get("excel_column")
-					if c, ok := column[tag]; ok {
-						if nil == err {
-							xlsx.SetCellStyle(s.Name, fmt.Sprintf("%s%d", c, index+2),
-								fmt.Sprintf("%s%d", c, index+2), style)
-						}
-						if len(c.Style) != 0 {
-							columnStyle, err := xlsx.NewStyle(c.Style)
-							if nil == err {
-								xlsx.SetCellStyle(s.Name, fmt.Sprintf("%s%d", c.cell, index+2),
-									fmt.Sprintf("%s%d", c.cell, index+2), columnStyle)
-							}
-						}
-						val := reflect.ValueOf(r).Field(j).Interface()
-						if f, ok := formatter[tag]; ok {
-							val = f.format(reflect.ValueOf(r).Field(j).Interface())
-						}
-						xlsx.SetCellValue(s.Name, fmt.Sprintf("%s%d", c.cell, index+2), val)
-						if c.Merge {
-							if m, ok := merge[tag]; ok {
-								v := fmt.Sprintf("%v", val)
-								if v != m.Val {
-									if index-m.Start > -1 && "" != m.Val {
-										// 合并列
-										if len(m.Exclude) == 0 || !strings.HasPrefix(m.Val, m.Exclude) {
-											xlsx.MergeCell(s.Name, fmt.Sprintf("%s%d", m.Col, m.Start),
-												fmt.Sprintf("%s%d", m.Col, index+1))
-										}
-									}
-									merge[tag] = mergeItem{
-										Col:     c.cell,
-										Start:   index + 2,
-										End:     0,
-										Val:     fmt.Sprintf("%v", val),
-										Exclude: c.MergeExclude,
-									}
-								}
-							} else {
-								merge[tag] = mergeItem{
-									Col:     c.cell,
-									Start:   index + 2,
-									End:     0,
-									Val:     fmt.Sprintf("%v", val),
-									Exclude: c.MergeExclude,
-								}
-							}
-						}
+				} else {
+					merge[ec.title] = mergeItem{
+						Col:     c.cell,
+						Start:   index + 2,
+						End:     0,
+						Val:     fmt.Sprintf("%v", val),
+						Exclude: c.MergeExclude,
 					}
 				}
 			}
 		}
-
+		if err = setRow(index+2, cells); nil != err {
+			DefaultLogger.Error(err.Error())
+			return err
+		}
+	}
+	if err = source.Err(); nil != err {
+		DefaultLogger.Error(err.Error())
+		return err
+	}
+	if size > 0 {
+		for cell, items := range validations {
+			dvRange := excelize.NewDataValidation(true)
+			dvRange.Sqref = fmt.Sprintf("%s2:%s%d", cell, cell, size+1)
+			err = dvRange.SetDropList(items)
+			if nil != err {
+				DefaultLogger.Error(err.Error())
+				//return nil, err
+			}
+			err = xlsx.AddDataValidation(s.Name, dvRange)
+			if nil != err {
+				DefaultLogger.Error(err.Error())
+				//return nil, err
+			}
+		}
 		for _, v := range merge {
 			if size-v.Start > 0 && "" != v.Val {
 				// 合并列
@@ -428,171 +513,236 @@ func Export(sheets []Sheet) (*bytes.Buffer, error) {
 				}
 			}
 		}
-		for _, p := range s.Panes {
-			xlsx.SetPanes(s.Name, p)
+	}
+	if nil != sw {
+		// 合并及下拉列表在Flush时写入
+		if err = sw.Flush(); nil != err {
+			DefaultLogger.Error(err.Error())
+			return err
 		}
 	}
-
-	xlsx.SetActiveSheet(last)
-	return xlsx.WriteToBuffer()
+	return nil
 }
 
-// Import import excel
+// Import import excel，字段可用validate tag校验，见validateRule；
+// 有错误的行不会写入Result，全部导入后返回*ImportReport，可用其Annotate生成标注了错误的文件
 func Import(reader io.Reader, sheets map[string]Sheet) error {
 	xlsx, err := excelize.OpenReader(reader)
 	if nil != err {
 		DefaultLogger.Error(err.Error())
 		return err
 	}
+	report := &ImportReport{}
 	for k, s := range sheets {
-		rows, err := xlsx.Rows(k)
+		list := make([]interface{}, 0)
+		err = importSheet(xlsx, k, s.T, report, func(row int, bean interface{}) error {
+			list = append(list, bean)
+			return nil
+		})
 		if nil != err {
-			DefaultLogger.Error(err.Error())
 			return err
 		}
-		i := 0
-		formatter := make(map[string]Format, 0)
-
-		m := make(map[int]field, 0)
-		list := make([]interface{}, 0)
-		for rows.Next() {
-			row, err := rows.Columns()
-			if err != nil {
-				DefaultLogger.Error(err)
-				return err
-			}
-			bean := reflect.New(s.T)
-			for j, colCell := range row {
-				if i == 0 {
-					err = handleImportHeader(s.T, &m, &formatter, j, colCell)
-					if err != nil {
-						DefaultLogger.Error(err)
-						return err
-					}
-				} else {
-					if k, ok := m[j]; ok {
-						v := bean.Elem()
-						if f, ok := formatter[k.Tag]; ok {
-							colCell = f.format(colCell)
-						}
-						convert(colCell, v.Field(k.Idx))
-					}
-				}
-			}
-			//fmt.Println(bean.Interface())
-			if i != 0 {
-				list = append(list, bean.Interface())
-			}
-			i++
-		}
 		if nil == s.Result {
 			s.Result = new([]interface{})
 		}
 		*(s.Result) = list
 	}
+	if len(report.Issues) > 0 {
+		return report
+	}
+	return nil
+}
 
-	//b, err := json.Marshal(sheets)
-	//if nil != err {
-	//	core.Logger.Error(err.Error())
-	//	return err
-	//}
-	//fmt.Println(string(b))
+// ImportEach 逐行导入sheet，每解码一行调用fn，row为该行在Excel中的行号(表头为第1行)，bean为t的指针；
+// 不保留已导入的行，有错误的行不调用fn，结束后返回*ImportReport；
+// fn返回ErrStopImport(或包装了它的错误)时停止导入，返回其他错误时停止导入并返回该错误
+func ImportEach(reader io.Reader, sheet string, t reflect.Type, fn func(row int, bean interface{}) error) error {
+	xlsx, err := excelize.OpenReader(reader)
+	if nil != err {
+		DefaultLogger.Error(err.Error())
+		return err
+	}
+	report := &ImportReport{}
+	err = importSheet(xlsx, sheet, t, report, fn)
+	if nil != err && !errors.Is(err, ErrStopImport) {
+		return err
+	}
+	if len(report.Issues) > 0 {
+		return report
+	}
 	return nil
 }
 
-func handleImportHeader(t reflect.Type, m *map[int]field, formatter *map[string]Format, idx int, col string) error {
-	switch t.Kind() {
-	case reflect.Ptr:
-		for k := 0; k < t.Elem().NumField(); k++ {
-			tag := t.Elem().Field(k).Tag.Get("excel_column")
-			if tag == col {
-				(*m)[idx] = field{
-					Idx: k,
-					Tag: tag,
-				}
-				form := t.Elem().Field(idx).Tag.Get("excel_formatter")
-				if "" != form {
-					var f Formatter
-					v, err := url.ParseQuery(form)
-					if nil != err {
-						DefaultLogger.Error(err.Error())
-						return err
-					}
-					err = DecodeQuery(&f, v)
-					if nil != err {
-						DefaultLogger.Error(err.Error())
-						return err
-					}
-					if "" != f.Enum {
-						list := strings.Split(f.Enum, ",")
-						if len(list) > 0 {
-							enum := enumFormatter{enum: make(map[string]string, 0)}
-							for i := 0; i < len(list); i++ {
-								enum.enum[list[i][:strings.Index(list[i], ":")]] = list[i][strings.Index(list[i], ":")+1:]
-							}
-							(*formatter)[tag] = enum
-						}
-					}
-					if "" != f.Time {
-						(*formatter)[tag] = timeImportFormatter{timeLayout: f.Time}
-					}
+// ImportBatch 分批导入sheet，每解码size行调用一次fn，最后一批不足size行；first为该批第一行在Excel中的行号，
+// 错误及回调返回值的处理同ImportEach
+func ImportBatch(reader io.Reader, sheet string, t reflect.Type, size int, fn func(first int, beans []interface{}) error) error {
+	if size <= 0 {
+		size = DefaultBatchRows
+	}
+	first := 0
+	stopped := false
+	beans := make([]interface{}, 0, size)
+	err := ImportEach(reader, sheet, t, func(row int, bean interface{}) error {
+		if len(beans) == 0 {
+			first = row
+		}
+		beans = append(beans, bean)
+		if len(beans) < size {
+			return nil
+		}
+		err := fn(first, beans)
+		beans = make([]interface{}, 0, size)
+		stopped = errors.Is(err, ErrStopImport)
+		return err
+	})
+	if _, ok := err.(*ImportReport); (nil != err && !ok) || stopped || len(beans) == 0 {
+		return err
+	}
+	if e := fn(first, beans); nil != e && !errors.Is(e, ErrStopImport) {
+		return e
+	}
+	return err
+}
+
+// importSheet 按表头匹配列，逐行解码为t的指针并调用fn，t为结构体或结构体指针类型；
+// 单元格的错误记录到report，有错误的行及空行不调用fn，缺少必填列时不导入任何行
+func importSheet(xlsx *excelize.File, sheet string, t reflect.Type, report *ImportReport, fn func(row int, bean interface{}) error) error {
+	if nil == t {
+		DefaultLogger.Error("无法识别类型")
+		return errors.New("无法识别类型")
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	rows, err := xlsx.Rows(sheet)
+	if nil != err {
+		DefaultLogger.Error(err.Error())
+		return err
+	}
+	return importRows(sheet, t, func() ([]string, error) {
+		if !rows.Next() {
+			return nil, io.EOF
+		}
+		return rows.Columns()
+	}, report, fn)
+}
+
+// importRows 逐行读取next直到返回io.EOF，第一行为表头，其余同importSheet；Excel及csv导入共用
+func importRows(sheet string, t reflect.Type, next func() ([]string, error), report *ImportReport, fn func(row int, bean interface{}) error) error {
+	list, err := excelColumns(t)
+	if nil != err {
+		DefaultLogger.Error(err.Error())
+		return err
+	}
+	formatter := make(map[string]importFormat, 0)
+	m := make(map[int]excelColumn, 0)
+	indexes := make([]int, 0)
+	for i := 0; ; i++ {
+		row, err := next()
+		if io.EOF == err {
+			break
+		}
+		if err != nil {
+			DefaultLogger.Error(err)
+			return err
+		}
+		if i == 0 {
+			for j, colCell := range row {
+				handleImportHeader(list, m, formatter, j, colCell)
+			}
+			missing := false
+			for _, c := range list {
+				if _, ok := findColumn(m, c.title); !ok && required(c.rules) {
+					report.add(ImportIssue{Sheet: sheet, Row: 1, Header: c.title, Reason: "缺少必填列"})
+					missing = true
 				}
-				break
 			}
+			if missing {
+				return nil
+			}
+			for j := range m {
+				indexes = append(indexes, j)
+			}
+			sort.Ints(indexes)
+			continue
+		}
+		if "" == strings.Join(row, "") {
+			continue
 		}
-	case reflect.Struct:
-		for k := 0; k < t.NumField(); k++ {
-			tag := t.Field(k).Tag.Get("excel_column")
-			if tag == col {
-				(*m)[idx] = field{
-					Idx: k,
-					Tag: tag,
+		bean := reflect.New(t)
+		valid := true
+		for _, j := range indexes {
+			c := m[j]
+			raw := ""
+			if j < len(row) {
+				raw = row[j]
+			}
+			field := bean.Elem().Field(c.field)
+			reason := ""
+			if "" != raw {
+				value := raw
+				if f, ok := formatter[c.title]; ok {
+					value, err = f.parse(raw)
 				}
-				form := t.Field(idx).Tag.Get("excel_formatter")
-				if "" != form {
-					var f Formatter
-					v, err := url.ParseQuery(form)
-					if nil != err {
-						DefaultLogger.Error(err.Error())
-						return err
-					}
-					err = DecodeQuery(&f, v)
-					if nil != err {
-						DefaultLogger.Error(err.Error())
-						return err
-					}
-					if "" != f.Enum {
-						list := strings.Split(f.Enum, ",")
-						if len(list) > 0 {
-							enum := enumFormatter{enum: make(map[string]string, 0)}
-							for i := 0; i < len(list); i++ {
-								enum.enum[list[i][strings.Index(list[i], ":")+1:]] = list[i][:strings.Index(list[i], ":")]
-							}
-							(*formatter)[tag] = enum
-						}
-					}
-					if "" != f.Time {
-						(*formatter)[tag] = timeImportFormatter{timeLayout: f.Time}
-					}
+				if nil == err {
+					err = convert(value, field)
+				}
+				if nil != err {
+					reason = err.Error()
+					err = nil
 				}
-				break
+			}
+			if "" == reason {
+				reason = validate(c.rules, raw, field)
+			}
+			if "" != reason {
+				valid = false
+				report.add(ImportIssue{Sheet: sheet, Row: i + 1, Column: c.cell, Header: c.title, Value: raw, Reason: reason})
 			}
 		}
-	default:
-
+		if !valid {
+			continue
+		}
+		if err = fn(i+1, bean.Interface()); nil != err {
+			return err
+		}
 	}
 	return nil
 }
 
-func convert(s string, v reflect.Value) {
+// findColumn 表头中标题对应的列
+func findColumn(m map[int]excelColumn, title string) (int, bool) {
+	for j, c := range m {
+		if c.title == title {
+			return j, true
+		}
+	}
+	return 0, false
+}
+
+// handleImportHeader 按表头匹配excel_column的标题，记录第idx列对应的字段及formatter，列名取表头中的实际位置
+func handleImportHeader(list []excelColumn, m map[int]excelColumn, formatter map[string]importFormat, idx int, col string) {
+	for _, c := range list {
+		if c.title == col {
+			c.cell, _ = excelize.ColumnNumberToName(idx + 1)
+			m[idx] = c
+			if f := c.format.importFormatter(); nil != f {
+				formatter[c.title] = f
+			}
+			return
+		}
+	}
+}
+
+// convert 将单元格的值转换后写入字段，无法转换时返回错误，字段保持零值
+func convert(s string, v reflect.Value) error {
 	switch v.Kind() {
 	case reflect.Ptr:
 		switch v.Type().String() {
 		case "*int":
 			val, err := strconv.Atoi(s)
 			if nil != err {
-				DefaultLogger.Error(err.Error())
-				break
+				return errors.New("无法转换为整数")
 			}
 			p := new(int)
 			*p = val
@@ -600,8 +750,7 @@ func convert(s string, v reflect.Value) {
 		case "*int64":
 			val, err := strconv.ParseInt(s, 10, 64)
 			if nil != err {
-				DefaultLogger.Error(err.Error())
-				break
+				return errors.New("无法转换为整数")
 			}
 			p := new(int64)
 			*p = val
@@ -613,8 +762,7 @@ func convert(s string, v reflect.Value) {
 		case "*float32":
 			val, err := strconv.ParseFloat(s, 32)
 			if nil != err {
-				DefaultLogger.Error(err.Error())
-				break
+				return errors.New("无法转换为数字")
 			}
 			p := new(float32)
 			*p = float32(val)
@@ -622,54 +770,50 @@ func convert(s string, v reflect.Value) {
 		case "*float64":
 			val, err := strconv.ParseFloat(s, 64)
 			if nil != err {
-				DefaultLogger.Error(err.Error())
-				break
+				return errors.New("无法转换为数字")
 			}
 			p := new(float64)
 			*p = val
 			v.Set(reflect.ValueOf(p))
 		case "*bool":
-			b := false
-			if "true" == s {
-				b = true
+			val, err := strconv.ParseBool(s)
+			if nil != err {
+				return errors.New("无法转换为布尔值")
 			}
 			p := new(bool)
-			*p = b
+			*p = val
 			v.Set(reflect.ValueOf(p))
 		default:
-			DefaultLogger.Error(v.Type().String(), "not support")
+			return fmt.Errorf("不支持的字段类型 %s", v.Type())
 		}
 	case reflect.String:
 		v.SetString(s)
 	case reflect.Int:
 		val, err := strconv.ParseInt(s, 10, 32)
 		if nil != err {
-			DefaultLogger.Error(err.Error())
-			break
+			return errors.New("无法转换为整数")
 		}
 		v.SetInt(val)
 	case reflect.Int64:
 		val, err := strconv.ParseInt(s, 10, 64)
 		if nil != err {
-			DefaultLogger.Error(err.Error())
-			break
+			return errors.New("无法转换为整数")
 		}
 		v.SetInt(val)
 	case reflect.Float32, reflect.Float64:
 		val, err := strconv.ParseFloat(s, 64)
 		if nil != err {
-			DefaultLogger.Error(err.Error())
-			break
+			return errors.New("无法转换为数字")
 		}
 		v.SetFloat(val)
 	case reflect.Bool:
-		b := false
-		if "true" == s {
-			b = true
+		val, err := strconv.ParseBool(s)
+		if nil != err {
+			return errors.New("无法转换为布尔值")
 		}
-		v.SetBool(b)
+		v.SetBool(val)
 	default:
-		fmt.Println(v.Kind())
-		DefaultLogger.Error(v.Kind(), "not support")
+		return fmt.Errorf("不支持的字段类型 %s", v.Type())
 	}
+	return nil
 }
@@ -0,0 +1,290 @@
+package pocket
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	excelize "github.com/360EntSecGroup-Skylar/excelize/v2"
+)
+
+type importUser struct {
+	Name string `excel_column:"姓名" validate:"required"`
+	Age  int    `excel_column:"年龄" validate:"min=1"`
+}
+
+func importWorkbook(t *testing.T) []byte {
+	rows := []interface{}{importUser{"a", 1}, importUser{"b", 2}, importUser{"c", 3}}
+	buf, err := Export([]Sheet{{Name: "Sheet1", Content: rows}})
+	if nil != err {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestImportEachWrappedStop(t *testing.T) {
+	data := importWorkbook(t)
+	count := 0
+	err := ImportEach(bytes.NewReader(data), "Sheet1", reflect.TypeOf(importUser{}), func(row int, bean interface{}) error {
+		count++
+		if count == 2 {
+			return fmt.Errorf("enough: %w", ErrStopImport)
+		}
+		return nil
+	})
+	if nil != err || count != 2 {
+		t.Errorf("ImportEach wrapped stop = %v after %d rows, want nil after 2", err, count)
+	}
+
+	batches := 0
+	err = ImportBatch(bytes.NewReader(data), "Sheet1", reflect.TypeOf(importUser{}), 2, func(first int, beans []interface{}) error {
+		batches++
+		return fmt.Errorf("first batch only: %w", ErrStopImport)
+	})
+	if nil != err || batches != 1 {
+		t.Errorf("ImportBatch wrapped stop = %v after %d batches, want nil after 1", err, batches)
+	}
+
+	failed := errors.New("failed")
+	err = ImportEach(bytes.NewReader(data), "Sheet1", reflect.TypeOf(importUser{}), func(row int, bean interface{}) error {
+		return failed
+	})
+	if err != failed {
+		t.Errorf("ImportEach err = %v, want callback error", err)
+	}
+}
+
+func TestImportCsvEachWrappedStop(t *testing.T) {
+	in := "姓名,年龄\na,1\nb,2\nc,3\n"
+	count := 0
+	err := ImportCsvEach(strings.NewReader(in), reflect.TypeOf(importUser{}), CsvOption{}, func(row int, bean interface{}) error {
+		count++
+		if count == 2 {
+			return fmt.Errorf("enough: %w", ErrStopImport)
+		}
+		return nil
+	})
+	if nil != err || count != 2 {
+		t.Errorf("ImportCsvEach wrapped stop = %v after %d rows, want nil after 2", err, count)
+	}
+}
+
+type importFlag struct {
+	Name string `excel_column:"名称"`
+	OK   bool   `excel_column:"ok"`
+	On   *bool  `excel_column:"on"`
+}
+
+func TestImportBool(t *testing.T) {
+	xlsx := excelize.NewFile()
+	rows := [][]interface{}{
+		{"名称", "ok", "on"},
+		{"a", "TRUE", "1"},
+		{"b", "0", "f"},
+		{"c", "yes", "真"},
+	}
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := xlsx.SetSheetRow("Sheet1", cell, &row); nil != err {
+			t.Fatal(err)
+		}
+	}
+	buf, err := xlsx.WriteToBuffer()
+	if nil != err {
+		t.Fatal(err)
+	}
+	result := make([]interface{}, 0)
+	err = Import(bytes.NewReader(buf.Bytes()), map[string]Sheet{"Sheet1": {Name: "Sheet1", T: reflect.TypeOf(importFlag{}), Result: &result}})
+	var report *ImportReport
+	if !errors.As(err, &report) || len(report.Issues) != 2 {
+		t.Fatalf("import err = %v, want 2 issues", err)
+	}
+	for i, column := range []string{"B", "C"} {
+		if issue := report.Issues[i]; issue.Row != 4 || issue.Column != column {
+			t.Errorf("issue %d = %v, want row 4 column %s", i, issue, column)
+		}
+	}
+	if len(result) != 2 {
+		t.Fatalf("imported %d rows, want 2", len(result))
+	}
+	a, b := result[0].(*importFlag), result[1].(*importFlag)
+	if !a.OK || !*a.On || b.OK || *b.On {
+		t.Errorf("imported %+v %+v", a, b)
+	}
+}
+
+type streamUser struct {
+	Group  string `excel_column:"分组"`
+	Name   string `excel_column:"姓名"`
+	Status int    `excel_column:"状态" excel_formatter:"enum=1:启用,2:停用"`
+}
+
+// streamUsers 以ChanSource逐行提供的数据
+func streamUsers(ctx context.Context) RowSource {
+	ch := make(chan interface{})
+	go func() {
+		defer close(ch)
+		for i, g := range []string{"g1", "g1", "g2", "g2", "g2", "g3"} {
+			ch <- &streamUser{Group: g, Name: fmt.Sprintf("u%d", i), Status: 1 + i%2}
+		}
+	}()
+	return ChanSource(ctx, ch)
+}
+
+func TestExportStream(t *testing.T) {
+	var buf bytes.Buffer
+	err := ExportStream(&buf, []Sheet{{
+		Name:         "Sheet1",
+		T:            reflect.TypeOf(streamUser{}),
+		Source:       streamUsers(context.Background()),
+		HeaderStyle:  headerStyle,
+		ContentStyle: contentStyle,
+		Columns:      map[string]Column{"分组": {Merge: true}, "姓名": {Width: 30}},
+	}})
+	if nil != err {
+		t.Fatal(err)
+	}
+	xlsx, err := excelize.OpenReader(bytes.NewReader(buf.Bytes()))
+	if nil != err {
+		t.Fatal(err)
+	}
+	rows, err := xlsx.GetRows("Sheet1")
+	if nil != err || len(rows) != 7 || rows[0][2] != "状态" || rows[1][1] != "u0" || rows[2][2] != "停用" {
+		t.Fatalf("rows = %v, %v", rows, err)
+	}
+	header, _ := xlsx.GetCellStyle("Sheet1", "A1")
+	content, _ := xlsx.GetCellStyle("Sheet1", "B2")
+	if 0 == header || 0 == content || header == content {
+		t.Errorf("header style %d, content style %d", header, content)
+	}
+	if width, err := xlsx.GetColWidth("Sheet1", "B"); nil != err || width != 30 {
+		t.Errorf("column B width = %v, %v, want 30", width, err)
+	}
+	merges, err := xlsx.GetMergeCells("Sheet1")
+	ranges := make([]string, 0)
+	for _, m := range merges {
+		ranges = append(ranges, m.GetStartAxis()+":"+m.GetEndAxis())
+	}
+	sort.Strings(ranges)
+	if nil != err || !reflect.DeepEqual(ranges, []string{"A2:A3", "A4:A6"}) {
+		t.Errorf("merges = %v, %v, want A2:A3 A4:A6", ranges, err)
+	}
+
+	// excelize v2.3.2没有读取数据验证的接口，直接检查sheet的xml
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if nil != err {
+		t.Fatal(err)
+	}
+	found := false
+	for _, f := range zr.File {
+		if "xl/worksheets/sheet1.xml" != f.Name {
+			continue
+		}
+		found = true
+		r, err := f.Open()
+		if nil != err {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(r)
+		r.Close()
+		if nil != err {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(data, []byte(`sqref="C2:C7"`)) || !bytes.Contains(data, []byte(`"启用,停用"`)) {
+			t.Errorf("enum drop list not found in sheet xml")
+		}
+	}
+	if !found {
+		t.Errorf("sheet1.xml not found")
+	}
+}
+
+func TestExportStreamCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan interface{})
+	go func() {
+		ch <- &streamUser{Group: "g1", Name: "a", Status: 1}
+		ch <- &streamUser{Group: "g1", Name: "b", Status: 2}
+		cancel()
+	}()
+	var buf bytes.Buffer
+	err := ExportStream(&buf, []Sheet{{Name: "Sheet1", Source: ChanSource(ctx, ch)}})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled export err = %v, want context.Canceled", err)
+	}
+	if buf.Len() > 0 {
+		t.Errorf("canceled export wrote %d bytes", buf.Len())
+	}
+}
+
+// wideType n个string字段的结构体类型，字段i的tag为excel_column:"c<i>"，index中指定的字段附加列号
+func wideType(n int, index map[int]int) reflect.Type {
+	fields := make([]reflect.StructField, n)
+	for i := range fields {
+		tag := fmt.Sprintf("c%d", i)
+		if number, ok := index[i]; ok {
+			tag += fmt.Sprintf(",index=%d", number)
+		}
+		fields[i] = reflect.StructField{
+			Name: fmt.Sprintf("F%d", i),
+			Type: reflect.TypeOf(""),
+			Tag:  reflect.StructTag(fmt.Sprintf(`excel_column:"%s"`, tag)),
+		}
+	}
+	return reflect.StructOf(fields)
+}
+
+func TestExcelColumns(t *testing.T) {
+	wide := wideType(30, map[int]int{0: 28})
+	list, err := excelColumns(wide)
+	if nil != err || len(list) != 30 {
+		t.Fatalf("columns = %d, %v", len(list), err)
+	}
+	cells := make(map[string]string)
+	for _, c := range list {
+		cells[c.title] = c.cell
+	}
+	want := map[string]string{"c1": "A", "c26": "Z", "c27": "AA", "c0": "AB", "c28": "AC", "c29": "AD"}
+	for title, cell := range want {
+		if cells[title] != cell {
+			t.Errorf("%s in column %s, want %s", title, cells[title], cell)
+		}
+	}
+
+	row := reflect.New(wide).Elem()
+	for i := 0; i < wide.NumField(); i++ {
+		row.Field(i).SetString(fmt.Sprintf("v%d", i))
+	}
+	buf, err := Export([]Sheet{{Name: "Sheet1", Content: []interface{}{row.Interface()}}})
+	if nil != err {
+		t.Fatal(err)
+	}
+	xlsx, err := excelize.OpenReader(bytes.NewReader(buf.Bytes()))
+	if nil != err {
+		t.Fatal(err)
+	}
+	for cell, value := range map[string]string{"AB1": "c0", "AB2": "v0", "AD2": "v29", "AA1": "c27"} {
+		if v, _ := xlsx.GetCellValue("Sheet1", cell); v != value {
+			t.Errorf("%s = %q, want %q", cell, v, value)
+		}
+	}
+	result := make([]interface{}, 0)
+	err = Import(bytes.NewReader(buf.Bytes()), map[string]Sheet{"Sheet1": {Name: "Sheet1", T: wide, Result: &result}})
+	if nil != err || len(result) != 1 || !reflect.DeepEqual(reflect.ValueOf(result[0]).Elem().Interface(), row.Interface()) {
+		t.Errorf("import = %v, %v", result, err)
+	}
+
+	if _, err = excelColumns(wideType(30, map[int]int{3: 27, 20: 27})); nil == err || !strings.Contains(err.Error(), "c20: 列号 27 重复") {
+		t.Errorf("duplicate index err = %v", err)
+	}
+	if _, err = excelColumns(wideType(2, map[int]int{1: 0})); nil == err {
+		t.Errorf("index=0 should be rejected")
+	}
+}
@@ -0,0 +1,329 @@
+// Package pocket Create at 2026-10-18 10:55
+package pocket
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// DBTX *sql.DB 与 *sql.Tx 的公共方法
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Executor 执行SqlBuilder生成的语句，并将结果映射到带db tag的结构体
+type Executor struct {
+	db     DBTX
+	logger *Logback
+	slow   time.Duration
+}
+
+// NewExecutor db为*sql.DB或*sql.Tx
+func NewExecutor(db DBTX) *Executor {
+	return &Executor{db: db}
+}
+
+// Logger 设置语句日志，每条语句以INFO记录调用位置、耗时、影响行数及代入参数后的sql，出错时为ERROR；
+// 未设置时只以DefaultLogger记录出错信息
+func (e *Executor) Logger(logger *Logback) *Executor {
+	e.logger = logger
+	return e
+}
+
+// SlowThreshold 慢查询阈值，耗时不小于d的语句以WARN记录，d<=0时不区分
+func (e *Executor) SlowThreshold(d time.Duration) *Executor {
+	e.slow = d
+	return e
+}
+
+// Exec 执行sql
+func (e *Executor) Exec(ctx context.Context, query string, params ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	result, err := e.db.ExecContext(ctx, query, params...)
+	if nil != err {
+		e.queryLog(start, query, params, -1, err)
+		return nil, err
+	}
+	rows, err := result.RowsAffected()
+	if nil != err {
+		rows = -1
+	}
+	e.queryLog(start, query, params, rows, nil)
+	return result, nil
+}
+
+// Insert 插入单条或批量数据，返回自增id；设置了Returning时返回RETURNING的第一列；
+// 方言不支持LastInsertId(PostgreSQL)时需设置Returning，否则不执行并返回ErrReturning，也可使用InsertReturning
+func (e *Executor) Insert(ctx context.Context, builder *SqlBuilder) (int64, error) {
+	if len(builder.returning) > 0 {
+		var id int64
+		if err := e.InsertReturning(ctx, builder, &id); nil != err {
+			return 0, err
+		}
+		return id, nil
+	}
+	if !builder.dialect.LastInsertId() {
+		return 0, &BuildError{Op: "insert", Row: -1, Detail: "set Returning", Err: ErrReturning}
+	}
+	query, params, err := insertQuery(builder)
+	if nil != err {
+		return 0, err
+	}
+	result, err := e.Exec(ctx, query, params...)
+	if nil != err {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// InsertReturning 插入单条或批量数据，将Returning设置的列扫描到dest：dest为结构体指针时按列名映射第一行，
+// 为结构体(指针)切片的指针时映射所有行，其他指针扫描第一行的第一列；没有返回行时返回sql.ErrNoRows
+func (e *Executor) InsertReturning(ctx context.Context, builder *SqlBuilder, dest interface{}) error {
+	if len(builder.returning) == 0 {
+		return &BuildError{Op: "returning", Row: -1, Detail: "no returning columns", Err: ErrReturning}
+	}
+	t := reflect.TypeOf(dest)
+	if nil == t || t.Kind() != reflect.Ptr {
+		return &BuildError{Op: "returning", Row: -1, Detail: fmt.Sprintf("%T", dest), Err: ErrModelKind}
+	}
+	query, params, err := insertQuery(builder)
+	if nil != err {
+		return err
+	}
+	start := time.Now()
+	rows, err := e.db.QueryContext(ctx, query, params...)
+	if nil != err {
+		e.queryLog(start, query, params, -1, err)
+		return err
+	}
+	defer rows.Close()
+	if t.Elem().Kind() == reflect.Slice {
+		err = ScanRows(rows, dest)
+		e.queryLog(start, query, params, int64(reflect.ValueOf(dest).Elem().Len()), err)
+		return err
+	}
+	if !rows.Next() {
+		err = rows.Err()
+		e.queryLog(start, query, params, 0, err)
+		if nil != err {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	if t.Elem().Kind() == reflect.Struct && t.Elem() != reflect.TypeOf(time.Time{}) {
+		columns, err := rows.Columns()
+		if nil == err {
+			err = scanStruct(rows, columns, reflect.ValueOf(dest).Elem())
+		}
+		e.queryLog(start, query, params, 1, err)
+		return err
+	}
+	columns, err := rows.Columns()
+	if nil == err {
+		values := make([]interface{}, len(columns))
+		values[0] = dest
+		for i := 1; i < len(values); i++ {
+			values[i] = new(interface{})
+		}
+		err = rows.Scan(values...)
+	}
+	e.queryLog(start, query, params, 1, err)
+	return err
+}
+
+// insertQuery Model为切片时生成批量插入语句，否则生成单条插入语句
+func insertQuery(builder *SqlBuilder) (string, []interface{}, error) {
+	if t := reflect.TypeOf(builder.Model); nil != t && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		return builder.BuildInsert()
+	}
+	return builder.BuildInsertRow()
+}
+
+// InsertBatch 分批插入，返回影响行数，参数同BuildInsertBatch
+func (e *Executor) InsertBatch(ctx context.Context, builder *SqlBuilder, maxRows, maxParams int) (int64, error) {
+	statements, err := builder.BuildInsertBatch(maxRows, maxParams)
+	if nil != err {
+		return 0, err
+	}
+	var total int64
+	for _, s := range statements {
+		result, err := e.Exec(ctx, s.Sql, s.Params...)
+		if nil != err {
+			return total, err
+		}
+		n, err := result.RowsAffected()
+		if nil != err {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// Upsert 插入或更新，返回影响行数
+func (e *Executor) Upsert(ctx context.Context, builder *SqlBuilder) (int64, error) {
+	query, params, err := builder.BuildUpsert()
+	if nil != err {
+		return 0, err
+	}
+	return e.affected(ctx, query, params)
+}
+
+// Update 更新，返回影响行数；带version乐观锁条件且未影响任何行时返回ErrOptimisticLock
+func (e *Executor) Update(ctx context.Context, builder *SqlBuilder) (int64, error) {
+	query, params, err := builder.BuildUpdate()
+	if nil != err {
+		return 0, err
+	}
+	n, err := e.affected(ctx, query, params)
+	if nil == err && n == 0 && builder.versionChecked() {
+		return 0, ErrOptimisticLock
+	}
+	return n, err
+}
+
+// Delete 删除，返回影响行数
+func (e *Executor) Delete(ctx context.Context, builder *SqlBuilder) (int64, error) {
+	query, params, err := builder.BuildDelete()
+	if nil != err {
+		return 0, err
+	}
+	return e.affected(ctx, query, params)
+}
+
+// Get 查询单条记录到dest，dest为结构体指针，无记录时返回sql.ErrNoRows
+func (e *Executor) Get(ctx context.Context, builder *SqlBuilder, dest interface{}) error {
+	t := reflect.TypeOf(dest)
+	if nil == t || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return &BuildError{Op: "get", Row: -1, Detail: fmt.Sprintf("%T", dest), Err: ErrModelKind}
+	}
+	query, params, err := builder.BuildSelect()
+	if nil != err {
+		return err
+	}
+	start := time.Now()
+	rows, err := e.db.QueryContext(ctx, query, params...)
+	if nil != err {
+		e.queryLog(start, query, params, -1, err)
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		err = rows.Err()
+		e.queryLog(start, query, params, 0, err)
+		if nil != err {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	columns, err := rows.Columns()
+	if nil == err {
+		err = scanStruct(rows, columns, reflect.ValueOf(dest).Elem())
+	}
+	if nil == err {
+		err = rows.Close()
+	}
+	e.queryLog(start, query, params, 1, err)
+	return err
+}
+
+// Find 查询多条记录到dest，dest为结构体切片或结构体指针切片的指针
+func (e *Executor) Find(ctx context.Context, builder *SqlBuilder, dest interface{}) error {
+	query, params, err := builder.BuildSelect()
+	if nil != err {
+		return err
+	}
+	start := time.Now()
+	rows, err := e.db.QueryContext(ctx, query, params...)
+	if nil != err {
+		e.queryLog(start, query, params, -1, err)
+		return err
+	}
+	defer rows.Close()
+	err = ScanRows(rows, dest)
+	count := int64(-1)
+	if v := reflect.Indirect(reflect.ValueOf(dest)); v.Kind() == reflect.Slice {
+		count = int64(v.Len())
+	}
+	e.queryLog(start, query, params, count, err)
+	return err
+}
+
+// Cursor 流式查询，model为结构体或结构体指针，决定每行解码的类型，用完或提前结束时需Close
+func (e *Executor) Cursor(ctx context.Context, builder *SqlBuilder, model interface{}) (*Cursor, error) {
+	query, params, err := builder.BuildSelect()
+	if nil != err {
+		return nil, err
+	}
+	start := time.Now()
+	rows, err := e.db.QueryContext(ctx, query, params...)
+	e.queryLog(start, query, params, -1, err)
+	if nil != err {
+		return nil, err
+	}
+	return NewCursor(ctx, rows, model)
+}
+
+func (e *Executor) affected(ctx context.Context, query string, params []interface{}) (int64, error) {
+	result, err := e.Exec(ctx, query, params...)
+	if nil != err {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ScanRows 将rows按列名映射到dest，dest为结构体切片或结构体指针切片的指针，列名对应db tag
+func ScanRows(rows *sql.Rows, dest interface{}) error {
+	t := reflect.TypeOf(dest)
+	if nil == t || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Slice {
+		return &BuildError{Op: "scan", Row: -1, Detail: fmt.Sprintf("%T", dest), Err: ErrModelKind}
+	}
+	elem := t.Elem().Elem()
+	isPtr := elem.Kind() == reflect.Ptr
+	if isPtr {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return &BuildError{Op: "scan", Row: -1, Detail: fmt.Sprintf("%T", dest), Err: ErrModelKind}
+	}
+	columns, err := rows.Columns()
+	if nil != err {
+		return err
+	}
+	list := reflect.ValueOf(dest).Elem()
+	for rows.Next() {
+		item := reflect.New(elem)
+		if err = scanStruct(rows, columns, item.Elem()); nil != err {
+			return err
+		}
+		if isPtr {
+			list = reflect.Append(list, item)
+		} else {
+			list = reflect.Append(list, item.Elem())
+		}
+	}
+	if err = rows.Err(); nil != err {
+		return err
+	}
+	reflect.ValueOf(dest).Elem().Set(list)
+	return nil
+}
+
+// scanStruct 扫描当前行到结构体v，没有对应字段的列忽略
+func scanStruct(rows *sql.Rows, columns []string, v reflect.Value) error {
+	meta := getModelMeta(v.Type())
+	dest := make([]interface{}, len(columns))
+	for i, c := range columns {
+		if f, ok := meta.columns[c]; ok {
+			dest[i] = f.alloc(v).Addr().Interface()
+		} else {
+			dest[i] = new(interface{})
+		}
+	}
+	return rows.Scan(dest...)
+}
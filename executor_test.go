@@ -0,0 +1,260 @@
+package pocket
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeResponse 假驱动对一条语句的响应
+type fakeResponse struct {
+	columns  []string
+	rows     [][]driver.Value
+	lastID   int64
+	noLastID bool
+	affected int64
+	err      error
+}
+
+// fakeHandler 按语句及参数返回响应
+type fakeHandler func(query string, args []driver.Value) fakeResponse
+
+var (
+	fakeOnce     sync.Once
+	fakeMu       sync.Mutex
+	fakeHandlers = make(map[string]fakeHandler)
+)
+
+// openFake 以handler打开一个假的*sql.DB
+func openFake(t *testing.T, handler fakeHandler) *sql.DB {
+	fakeOnce.Do(func() {
+		sql.Register("pocket-fake", fakeDriver{})
+	})
+	fakeMu.Lock()
+	fakeHandlers[t.Name()] = handler
+	fakeMu.Unlock()
+	db, err := sql.Open("pocket-fake", t.Name())
+	if nil != err {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeMu.Lock()
+	defer fakeMu.Unlock()
+	return &fakeConn{handler: fakeHandlers[name]}, nil
+}
+
+type fakeConn struct {
+	handler fakeHandler
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) { return fakeTx{conn: c}, nil }
+
+// fakeTx 提交及回滚以COMMIT、ROLLBACK语句交给handler
+type fakeTx struct {
+	conn *fakeConn
+}
+
+func (tx fakeTx) Commit() error   { return tx.conn.handler("COMMIT", nil).err }
+func (tx fakeTx) Rollback() error { return tx.conn.handler("ROLLBACK", nil).err }
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	r := s.conn.handler(s.query, args)
+	if nil != r.err {
+		return nil, r.err
+	}
+	return fakeResult{r}, nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	r := s.conn.handler(s.query, args)
+	if nil != r.err {
+		return nil, r.err
+	}
+	return &fakeRows{columns: r.columns, rows: r.rows}, nil
+}
+
+type fakeResult struct {
+	r fakeResponse
+}
+
+func (r fakeResult) LastInsertId() (int64, error) {
+	if r.r.noLastID {
+		return 0, errors.New("LastInsertId is not supported by this driver")
+	}
+	return r.r.lastID, nil
+}
+
+func (r fakeResult) RowsAffected() (int64, error) { return r.r.affected, nil }
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+	index   int
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.index >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.index])
+	r.index++
+	return nil
+}
+
+type execUser struct {
+	ID      *int64  `db:"id,pk"`
+	Name    *string `db:"name,set"`
+	Version *int64  `db:"version,version"`
+}
+
+func TestExecutorInsert(t *testing.T) {
+	ctx := context.Background()
+	name := "n"
+	var queries []string
+	db := openFake(t, func(query string, args []driver.Value) fakeResponse {
+		queries = append(queries, query)
+		switch {
+		case strings.Contains(query, "RETURNING"):
+			return fakeResponse{columns: []string{"id", "name"}, rows: [][]driver.Value{{int64(9), "n"}, {int64(10), "m"}}}
+		case strings.HasPrefix(query, `INSERT INTO "`):
+			return fakeResponse{noLastID: true, affected: 1}
+		default:
+			return fakeResponse{lastID: 7, affected: 1}
+		}
+	})
+	e := NewExecutor(db)
+
+	id, err := e.Insert(ctx, NewSqlBuilder("user", &execUser{Name: &name}))
+	if nil != err || id != 7 {
+		t.Errorf("mysql insert = %d, %v, want 7", id, err)
+	}
+	if _, err = e.Insert(ctx, NewSqlBuilder("user", &execUser{Name: &name}, PostgreSQL)); !errors.Is(err, ErrReturning) {
+		t.Errorf("postgres insert without returning err = %v, want ErrReturning", err)
+	}
+	id, err = e.Insert(ctx, NewSqlBuilder("user", &execUser{Name: &name}, PostgreSQL).Returning("id"))
+	if nil != err || id != 9 {
+		t.Errorf("postgres insert returning = %d, %v, want 9", id, err)
+	}
+
+	var user execUser
+	err = e.InsertReturning(ctx, NewSqlBuilder("user", &execUser{Name: &name}, PostgreSQL).Returning("id", "name"), &user)
+	if nil != err || nil == user.ID || *user.ID != 9 || *user.Name != "n" {
+		t.Errorf("insert returning struct = %+v, %v", user, err)
+	}
+	var users []*execUser
+	rows := []*execUser{{Name: &name}, {Name: &name}}
+	err = e.InsertReturning(ctx, NewSqlBuilder("user", rows, PostgreSQL).Returning("id", "name"), &users)
+	if nil != err || len(users) != 2 || *users[1].ID != 10 {
+		t.Errorf("insert returning slice = %v, %v", users, err)
+	}
+	var first int64
+	err = e.InsertReturning(ctx, NewSqlBuilder("user", &execUser{Name: &name}, SQLite).Returning("id", "name"), &first)
+	if nil != err || first != 9 {
+		t.Errorf("insert returning scalar = %d, %v", first, err)
+	}
+	if err = e.InsertReturning(ctx, NewSqlBuilder("user", &execUser{Name: &name}, PostgreSQL), &first); !errors.Is(err, ErrReturning) {
+		t.Errorf("insert returning without columns err = %v, want ErrReturning", err)
+	}
+	if len(queries) != 5 {
+		t.Errorf("executed %d statements, want 5: %v", len(queries), queries)
+	}
+}
+
+func TestExecutorUpdateOptimisticLock(t *testing.T) {
+	ctx := context.Background()
+	affected := int64(0)
+	var args []driver.Value
+	db := openFake(t, func(query string, a []driver.Value) fakeResponse {
+		args = a
+		return fakeResponse{affected: affected}
+	})
+	e := NewExecutor(db)
+	name, version := "n", int64(3)
+	builder := func() *SqlBuilder {
+		return NewSqlBuilder("user", &execUser{Name: &name, Version: &version}).Condition(Eq("id", 1))
+	}
+	if _, err := e.Update(ctx, builder()); !errors.Is(err, ErrOptimisticLock) {
+		t.Errorf("update with 0 rows err = %v, want ErrOptimisticLock", err)
+	}
+	if len(args) != 3 || args[2] != int64(3) {
+		t.Errorf("update args = %v, want version 3 last", args)
+	}
+	affected = 1
+	if n, err := e.Update(ctx, builder()); nil != err || n != 1 {
+		t.Errorf("update = %d, %v, want 1", n, err)
+	}
+}
+
+func TestExecutorQuery(t *testing.T) {
+	ctx := context.Background()
+	empty := false
+	db := openFake(t, func(query string, args []driver.Value) fakeResponse {
+		if empty {
+			return fakeResponse{columns: []string{"id", "name", "version"}}
+		}
+		return fakeResponse{
+			columns: []string{"id", "name", "extra", "version"},
+			rows:    [][]driver.Value{{int64(1), "a", "x", int64(1)}, {int64(2), "b", nil, nil}},
+		}
+	})
+	e := NewExecutor(db)
+
+	var list []execUser
+	if err := e.Find(ctx, NewSqlBuilder("user", &execUser{}), &list); nil != err || len(list) != 2 {
+		t.Fatalf("find = %v, %v", list, err)
+	}
+	if *list[0].Name != "a" || *list[1].ID != 2 || nil != list[1].Version {
+		t.Errorf("find mapped %+v %+v", list[0], list[1])
+	}
+	var one execUser
+	if err := e.Get(ctx, NewSqlBuilder("user", &execUser{}), &one); nil != err || *one.ID != 1 {
+		t.Errorf("get = %+v, %v", one, err)
+	}
+	cursor, err := e.Cursor(ctx, NewSqlBuilder("user", &execUser{}), &execUser{})
+	if nil != err {
+		t.Fatal(err)
+	}
+	count := 0
+	for cursor.Next() {
+		if _, ok := cursor.Value().(*execUser); !ok {
+			t.Errorf("cursor value %T", cursor.Value())
+		}
+		count++
+	}
+	if nil != cursor.Err() || count != 2 {
+		t.Errorf("cursor read %d rows, err %v", count, cursor.Err())
+	}
+
+	empty = true
+	if err := e.Get(ctx, NewSqlBuilder("user", &execUser{}), &one); err != sql.ErrNoRows {
+		t.Errorf("get without rows err = %v, want sql.ErrNoRows", err)
+	}
+}
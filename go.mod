@@ -7,4 +7,5 @@ require (
 	github.com/go-redis/redis/v8 v8.4.0
 	github.com/satori/go.uuid v1.2.0
 	github.com/sony/sonyflake v1.0.0
+	golang.org/x/text v0.3.3
 )
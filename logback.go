@@ -107,8 +107,31 @@ func (logback *Logback) Error(v ...interface{}) {
 }
 
 func (logback *Logback) output(level string, v ...interface{}) {
-	format := defaultFormat
 	file, line := getCaller(3)
+	logback.outputAt(level, file, line, v...)
+}
+
+// logAt 以指定的调用位置记录日志，用于库内代替调用方记录，如Executor的语句日志
+func (logback *Logback) logAt(level Level, file string, line int, v ...interface{}) {
+	if logback.level < level {
+		return
+	}
+	switch level {
+	case ErrorLevel:
+		logback.outputAt("ERROR", file, line, v...)
+	case WarnLevel:
+		logback.outputAt("WARN", file, line, v...)
+	case InfoLevel:
+		logback.outputAt("INFO", file, line, v...)
+	case DebugLevel:
+		logback.outputAt("DEBUG", file, line, v...)
+	default:
+		logback.outputAt("TRACE", file, line, v...)
+	}
+}
+
+func (logback *Logback) outputAt(level string, file string, line int, v ...interface{}) {
+	format := defaultFormat
 	fields := ""
 	str := ""
 	if nil != logback.fields {
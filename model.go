@@ -0,0 +1,199 @@
+// Package pocket Create at 2026-10-18 11:50
+package pocket
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// fieldMeta 带db tag的字段元数据
+type fieldMeta struct {
+	name    string            // 字段名
+	column  string            // 列名
+	options map[string]string // tag选项，`db:"column,sort=desc"` 为 {"sort": "desc"}
+	index   []int             // 字段路径，匿名嵌入结构体展开后用于FieldByIndex
+	typ     reflect.Type      // 字段类型
+	quoted  [3]string         // 各方言引用后的列名，下标为Dialect
+}
+
+// quote 方言引用后的列名
+func (f *fieldMeta) quote(d Dialect) string {
+	if int(d) < len(f.quoted) {
+		return f.quoted[d]
+	}
+	return d.Quote(f.column)
+}
+
+// option tag选项的值
+func (f *fieldMeta) option(name string) (string, bool) {
+	v, ok := f.options[name]
+	return v, ok
+}
+
+// has 是否带有tag选项
+func (f *fieldMeta) has(name string) bool {
+	_, ok := f.options[name]
+	return ok
+}
+
+// value 结构体v中该字段的值，路径上的嵌入指针为nil时返回无效的reflect.Value
+func (f *fieldMeta) value(v reflect.Value) reflect.Value {
+	for i, idx := range f.index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}
+			}
+			v = v.Elem()
+		}
+		v = v.Field(idx)
+	}
+	return v
+}
+
+// alloc 结构体v中该字段的值，路径上的嵌入指针为nil时创建，v需可寻址
+func (f *fieldMeta) alloc(v reflect.Value) reflect.Value {
+	for i, idx := range f.index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(idx)
+	}
+	return v
+}
+
+// present 字段是否生效，插入、更新、条件结构体及ChangesetOf使用相同的规则：指针及切片字段非nil时生效；
+// 值字段需在tag中选择，带omitempty时非零值生效，带zero时始终生效(含零值)，都不带时忽略
+func (f *fieldMeta) present(v reflect.Value) (reflect.Value, bool) {
+	field := f.value(v)
+	switch field.Kind() {
+	case reflect.Invalid:
+		return field, false
+	case reflect.Ptr, reflect.Slice:
+		return field, !field.IsNil()
+	default:
+		switch {
+		case f.has("zero"):
+			return field, true
+		case f.has("omitempty"):
+			return field, !field.IsZero()
+		default:
+			return field, false
+		}
+	}
+}
+
+// updatable 是否为可由Model更新的列，带set且不是pk、version及created列
+func (f *fieldMeta) updatable() bool {
+	return f.has("set") && !f.has("pk") && !f.has("version") && !f.has("created")
+}
+
+// modelMeta 结构体元数据
+type modelMeta struct {
+	fields  []*fieldMeta          // 按字段顺序排列的列
+	columns map[string]*fieldMeta // 列名索引
+	sorts   []sortColumn          // tag带sort的列
+	deleted *fieldMeta            // 软删除标记列
+	invalid []string              // 非法的列名
+}
+
+// modelCache reflect.Type -> *modelMeta
+var modelCache sync.Map
+
+// getModelMeta 结构体类型的元数据，每个类型只解析一次，并发安全
+func getModelMeta(t reflect.Type) *modelMeta {
+	if m, ok := modelCache.Load(t); ok {
+		return m.(*modelMeta)
+	}
+	m := &modelMeta{columns: make(map[string]*fieldMeta)}
+	m.walk(t, nil)
+	actual, _ := modelCache.LoadOrStore(t, m)
+	return actual.(*modelMeta)
+}
+
+// walk 解析结构体字段，未打tag的匿名结构体及结构体指针字段展开，列名重复时保留先出现的字段
+func (m *modelMeta) walk(t reflect.Type, index []int) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		path := make([]int, len(index)+1)
+		copy(path, index)
+		path[len(index)] = i
+		tag := f.Tag.Get("db")
+		if "" == tag {
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				m.walk(f.Type, path)
+			} else if f.Anonymous && f.Type.Kind() == reflect.Ptr && f.Type.Elem().Kind() == reflect.Struct {
+				m.walk(f.Type.Elem(), path)
+			}
+			continue
+		}
+		column, options := parseTag(tag)
+		if !validIdentifier(column) || strings.Contains(column, ".") {
+			m.invalid = append(m.invalid, column)
+			continue
+		}
+		if _, ok := m.columns[column]; ok {
+			continue
+		}
+		field := &fieldMeta{name: f.Name, column: column, options: options, index: path, typ: f.Type}
+		for d := range field.quoted {
+			field.quoted[d] = Dialect(d).Quote(column)
+		}
+		m.fields = append(m.fields, field)
+		m.columns[column] = field
+		if sort, ok := options["sort"]; ok {
+			m.sorts = append(m.sorts, sortColumn{column: column, desc: strings.EqualFold(sort, "desc")})
+		}
+		if _, ok := options["deleted"]; ok && nil == m.deleted {
+			m.deleted = field
+		}
+	}
+}
+
+// parseTag 解析tag，返回列名及选项；括号及引号内的逗号不作为分隔符，如 `db:"price,type=DECIMAL(10,2),default='a,b'"`
+func parseTag(tag string) (string, map[string]string) {
+	list := splitTag(tag)
+	options := make(map[string]string, len(list)-1)
+	for _, option := range list[1:] {
+		option = strings.TrimSpace(option)
+		if "" == option {
+			continue
+		}
+		if i := strings.Index(option, "="); i > 0 {
+			options[option[:i]] = option[i+1:]
+		} else {
+			options[option] = ""
+		}
+	}
+	return strings.TrimSpace(list[0]), options
+}
+
+// splitTag 按逗号分隔tag，忽略括号及单双引号内的逗号
+func splitTag(tag string) []string {
+	list := make([]string, 0)
+	depth := 0
+	var quote byte
+	start := 0
+	for i := 0; i < len(tag); i++ {
+		c := tag[i]
+		switch {
+		case 0 != quote:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')' && depth > 0:
+			depth--
+		case c == ',' && 0 == depth:
+			list = append(list, tag[start:i])
+			start = i + 1
+		}
+	}
+	return append(list, tag[start:])
+}
@@ -0,0 +1,215 @@
+package pocket
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseTag(t *testing.T) {
+	cases := []struct {
+		tag     string
+		column  string
+		options map[string]string
+	}{
+		{"name", "name", map[string]string{}},
+		{"name, set ,sort=desc", "name", map[string]string{"set": "", "sort": "desc"}},
+		{"price,type=DECIMAL(10,2),notnull", "price", map[string]string{"type": "DECIMAL(10,2)", "notnull": ""}},
+		{"tags,type=ENUM('a,b','c'),default='a,b'", "tags", map[string]string{"type": "ENUM('a,b','c')", "default": "'a,b'"}},
+		{`geo,type=NUMERIC(10,(2)),default="x,y"`, "geo", map[string]string{"type": "NUMERIC(10,(2))", "default": `"x,y"`}},
+	}
+	for _, c := range cases {
+		column, options := parseTag(c.tag)
+		if column != c.column || !reflect.DeepEqual(options, c.options) {
+			t.Errorf("parseTag(%q) = %q, %v, want %q, %v", c.tag, column, options, c.column, c.options)
+		}
+	}
+}
+
+type ddlProduct struct {
+	ID    int64    `db:"id,pk,auto"`
+	Price float64  `db:"price,type=DECIMAL(10,2),default=0.00"`
+	Rate  *float64 `db:"rate,type=NUMERIC(5,4),default=round(1.5,1)"`
+}
+
+func TestBuildCreateTableDecimal(t *testing.T) {
+	want := map[Dialect]string{
+		MySQL:      "CREATE TABLE `product` (`id` BIGINT NOT NULL AUTO_INCREMENT,`price` DECIMAL(10,2) NOT NULL DEFAULT 0.00,`rate` NUMERIC(5,4) DEFAULT round(1.5,1),PRIMARY KEY (`id`))",
+		PostgreSQL: `CREATE TABLE "product" ("id" BIGSERIAL NOT NULL,"price" DECIMAL(10,2) NOT NULL DEFAULT 0.00,"rate" NUMERIC(5,4) DEFAULT round(1.5,1),PRIMARY KEY ("id"))`,
+		SQLite:     `CREATE TABLE "product" ("id" INTEGER PRIMARY KEY AUTOINCREMENT,"price" DECIMAL(10,2) NOT NULL DEFAULT 0.00,"rate" NUMERIC(5,4) DEFAULT round(1.5,1))`,
+	}
+	for d, sql := range want {
+		list, err := NewSqlBuilder("product", &ddlProduct{}, d).BuildCreateTable()
+		if nil != err || len(list) != 1 || list[0] != sql {
+			t.Errorf("%s create table = %v, %v, want %s", d, list, err, sql)
+		}
+	}
+}
+
+type benchUser struct {
+	ID        *int64   `db:"id,pk"`
+	Name      *string  `db:"name,set"`
+	Email     *string  `db:"email,set,unique"`
+	Phone     *string  `db:"phone,set"`
+	Age       *int     `db:"age,set"`
+	Score     *float64 `db:"score,set"`
+	CreatedAt *int64   `db:"created_at"`
+	UpdatedAt *int64   `db:"updated_at"`
+}
+
+func newBenchUser() *benchUser {
+	id, name, email, phone, age, score, now := int64(1), "n", "e", "p", 20, 1.5, int64(1600000000)
+	return &benchUser{ID: &id, Name: &name, Email: &email, Phone: &phone, Age: &age, Score: &score, CreatedAt: &now, UpdatedAt: &now}
+}
+
+// legacyInsertRow 缓存元数据前的实现：每次遍历字段并以strings.Index解析tag
+func legacyInsertRow(table string, model interface{}) (string, []interface{}) {
+	column := ""
+	values := ""
+	params := make([]interface{}, 0)
+	originType := reflect.TypeOf(model)
+	originValue := reflect.ValueOf(model)
+	for i := 0; i < originType.Elem().NumField(); i++ {
+		tag := originType.Elem().Field(i).Tag.Get("db")
+		if "" == tag {
+			continue
+		}
+		if originValue.Elem().Field(i).Kind() == reflect.Ptr && !originValue.Elem().Field(i).IsNil() {
+			if "" != column {
+				column += ","
+				values += ","
+			}
+			if strings.Index(tag, ",") > 0 {
+				column += "`" + tag[:strings.Index(tag, ",")] + "`"
+			} else {
+				column += "`" + tag + "`"
+			}
+			values += "?"
+			params = append(params, originValue.Elem().Field(i).Interface())
+		}
+	}
+	return "INSERT INTO `" + table + "`" + fmt.Sprintf("(%s) VALUES (%s)", column, values), params
+}
+
+// legacyInsert 缓存元数据前的批量插入实现
+func legacyInsert(table string, model interface{}) (string, []interface{}) {
+	column := ""
+	values := ""
+	params := make([]interface{}, 0)
+	originValue := reflect.ValueOf(model)
+	for j := 0; j < originValue.Len(); j++ {
+		item := originValue.Index(j).Elem()
+		itemType := item.Type()
+		row := ""
+		for i := 0; i < itemType.NumField(); i++ {
+			tag := itemType.Field(i).Tag.Get("db")
+			if "" == tag || item.Field(i).Kind() != reflect.Ptr || item.Field(i).IsNil() {
+				continue
+			}
+			if j == 0 {
+				if "" != column {
+					column += ","
+				}
+				if strings.Index(tag, ",") > 0 {
+					column += "`" + tag[:strings.Index(tag, ",")] + "`"
+				} else {
+					column += "`" + tag + "`"
+				}
+			}
+			if "" != row {
+				row += ","
+			}
+			row += "?"
+			params = append(params, item.Field(i).Interface())
+		}
+		if j > 0 {
+			values += ","
+		}
+		values += "(" + row + ")"
+	}
+	return "INSERT INTO `" + table + "`" + fmt.Sprintf("(%s) VALUES %s", column, values), params
+}
+
+func TestLegacyInsertEquivalent(t *testing.T) {
+	u := newBenchUser()
+	sql, params, err := NewSqlBuilder("user", u).BuildInsertRow()
+	legacy, legacyParams := legacyInsertRow("user", u)
+	if nil != err || sql != legacy || len(params) != len(legacyParams) {
+		t.Errorf("insert row = %s, %v, legacy %s", sql, err, legacy)
+	}
+	rows := []*benchUser{u, newBenchUser()}
+	sql, _, err = NewSqlBuilder("user", rows).BuildInsert()
+	legacy, _ = legacyInsert("user", rows)
+	if nil != err || sql != legacy {
+		t.Errorf("insert = %s, %v, legacy %s", sql, err, legacy)
+	}
+}
+
+func TestGetModelMetaConcurrent(t *testing.T) {
+	type concurrentModel struct {
+		ID   *int64  `db:"id,pk"`
+		Name *string `db:"name,set,sort=desc"`
+	}
+	typ := reflect.TypeOf(concurrentModel{})
+	metas := make([]*modelMeta, 32)
+	var wg sync.WaitGroup
+	for i := range metas {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			metas[i] = getModelMeta(typ)
+			NewSqlBuilder("c", &concurrentModel{}).BuildSelect()
+		}(i)
+	}
+	wg.Wait()
+	for _, m := range metas {
+		if m != metas[0] {
+			t.Fatal("getModelMeta returned different metadata for the same type")
+		}
+	}
+	if len(metas[0].fields) != 2 || len(metas[0].sorts) != 1 || !metas[0].sorts[0].desc {
+		t.Errorf("meta = %+v", metas[0])
+	}
+}
+
+func BenchmarkBuildInsertRow(b *testing.B) {
+	u := newBenchUser()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewSqlBuilder("user", u).BuildInsertRow()
+	}
+}
+
+func BenchmarkLegacyInsertRow(b *testing.B) {
+	u := newBenchUser()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		legacyInsertRow("user", u)
+	}
+}
+
+func benchRows() []*benchUser {
+	rows := make([]*benchUser, 100)
+	for i := range rows {
+		rows[i] = newBenchUser()
+	}
+	return rows
+}
+
+func BenchmarkBuildInsert(b *testing.B) {
+	rows := benchRows()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewSqlBuilder("user", rows).BuildInsert()
+	}
+}
+
+func BenchmarkLegacyInsert(b *testing.B) {
+	rows := benchRows()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		legacyInsert("user", rows)
+	}
+}
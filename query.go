@@ -0,0 +1,372 @@
+// Package pocket Create at 2026-10-18 12:20
+package pocket
+
+import (
+	"fmt"
+	"strings"
+)
+
+// join 关联表
+type join struct {
+	kind  string
+	table string
+	alias string
+	on    Expr
+}
+
+// Aggregate 聚合函数，用于SqlBuilder.Columns及Having
+type Aggregate struct {
+	fn     string
+	column string
+	alias  string
+}
+
+// Count COUNT(column)，column为*时统计行数
+func Count(column string) Aggregate {
+	return Aggregate{fn: "COUNT", column: column}
+}
+
+// Sum SUM(column)
+func Sum(column string) Aggregate {
+	return Aggregate{fn: "SUM", column: column}
+}
+
+// Avg AVG(column)
+func Avg(column string) Aggregate {
+	return Aggregate{fn: "AVG", column: column}
+}
+
+// Max MAX(column)
+func Max(column string) Aggregate {
+	return Aggregate{fn: "MAX", column: column}
+}
+
+// Min MIN(column)
+func Min(column string) Aggregate {
+	return Aggregate{fn: "MIN", column: column}
+}
+
+// As 设置别名
+func (a Aggregate) As(alias string) Aggregate {
+	a.alias = alias
+	return a
+}
+
+// Eq 聚合结果 = value
+func (a Aggregate) Eq(value interface{}) Expr {
+	return aggregateExpr{aggregate: a, op: "=", value: value}
+}
+
+// Ne 聚合结果 <> value
+func (a Aggregate) Ne(value interface{}) Expr {
+	return aggregateExpr{aggregate: a, op: "<>", value: value}
+}
+
+// Gt 聚合结果 > value
+func (a Aggregate) Gt(value interface{}) Expr {
+	return aggregateExpr{aggregate: a, op: ">", value: value}
+}
+
+// Gte 聚合结果 >= value
+func (a Aggregate) Gte(value interface{}) Expr {
+	return aggregateExpr{aggregate: a, op: ">=", value: value}
+}
+
+// Lt 聚合结果 < value
+func (a Aggregate) Lt(value interface{}) Expr {
+	return aggregateExpr{aggregate: a, op: "<", value: value}
+}
+
+// Lte 聚合结果 <= value
+func (a Aggregate) Lte(value interface{}) Expr {
+	return aggregateExpr{aggregate: a, op: "<=", value: value}
+}
+
+// expression 聚合函数表达式，不含别名
+func (a Aggregate) expression(d Dialect) string {
+	return a.fn + "(" + d.Quote(a.column) + ")"
+}
+
+// aggregateExpr 聚合结果比较条件，用于HAVING
+type aggregateExpr struct {
+	aggregate Aggregate
+	op        string
+	value     interface{}
+}
+
+func (e aggregateExpr) build(d Dialect) (string, []interface{}, error) {
+	if !validIdentifier(e.aggregate.column) {
+		return "", nil, buildErr("having", ErrInvalidIdentifier, e.aggregate.column)
+	}
+	return operand(d, e.aggregate.expression(d)+e.op, e.value)
+}
+
+// columnExpr 列与列比较条件，用于JOIN的ON
+type columnExpr struct {
+	left  string
+	op    string
+	right string
+}
+
+func (e columnExpr) build(d Dialect) (string, []interface{}, error) {
+	left, err := d.quoteIdent(e.left)
+	if nil != err {
+		return "", nil, err
+	}
+	right, err := d.quoteIdent(e.right)
+	if nil != err {
+		return "", nil, err
+	}
+	return left + e.op + right, []interface{}{}, nil
+}
+
+// EqCol left = right，两侧均为列，如 EqCol("u.id", "o.user_id")
+func EqCol(left, right string) Expr {
+	return columnExpr{left: left, op: "=", right: right}
+}
+
+// existsExpr EXISTS子查询
+type existsExpr struct {
+	query *SqlBuilder
+	not   bool
+}
+
+func (e existsExpr) build(d Dialect) (string, []interface{}, error) {
+	sql, params, err := e.query.subquery(d)
+	if nil != err {
+		return "", nil, err
+	}
+	if e.not {
+		return "NOT EXISTS " + sql, params, nil
+	}
+	return "EXISTS " + sql, params, nil
+}
+
+// Exists EXISTS (子查询)
+func Exists(query *SqlBuilder) Expr {
+	return existsExpr{query: query}
+}
+
+// NotExists NOT EXISTS (子查询)
+func NotExists(query *SqlBuilder) Expr {
+	return existsExpr{query: query, not: true}
+}
+
+// operand 生成 left 与值的比较，值为*SqlBuilder时作为子查询
+func operand(d Dialect, left string, value interface{}) (string, []interface{}, error) {
+	if query, ok := value.(*SqlBuilder); ok {
+		sql, params, err := query.subquery(d)
+		if nil != err {
+			return "", nil, err
+		}
+		return left + sql, params, nil
+	}
+	return left + "?", []interface{}{value}, nil
+}
+
+// As 设置表别名，用于关联查询，设置后Model的列以别名限定
+func (builder *SqlBuilder) As(alias string) *SqlBuilder {
+	builder.alias = alias
+	return builder
+}
+
+// Join INNER JOIN table alias ON on
+func (builder *SqlBuilder) Join(table string, alias string, on Expr) *SqlBuilder {
+	builder.joins = append(builder.joins, join{kind: " INNER JOIN ", table: table, alias: alias, on: on})
+	return builder
+}
+
+// LeftJoin LEFT JOIN table alias ON on
+func (builder *SqlBuilder) LeftJoin(table string, alias string, on Expr) *SqlBuilder {
+	builder.joins = append(builder.joins, join{kind: " LEFT JOIN ", table: table, alias: alias, on: on})
+	return builder
+}
+
+// Columns 设置查询列，覆盖Model的列，元素为列名(可带表别名，如 o.id、u.*)或Aggregate
+func (builder *SqlBuilder) Columns(columns ...interface{}) *SqlBuilder {
+	for _, c := range columns {
+		switch c.(type) {
+		case string, Aggregate:
+		default:
+			builder.err = &BuildError{Op: "select", Row: -1, Detail: fmt.Sprintf("%T", c), Err: ErrColumnType}
+		}
+	}
+	builder.columns = columns
+	return builder
+}
+
+// GroupBy GROUP BY columns，分组查询不使用Model中sort列的排序
+func (builder *SqlBuilder) GroupBy(columns ...string) *SqlBuilder {
+	builder.groupBy = columns
+	return builder
+}
+
+// Having HAVING条件，聚合条件使用Aggregate的比较方法，如 Count("*").Gt(1)
+func (builder *SqlBuilder) Having(expr Expr) *SqlBuilder {
+	builder.having = expr
+	return builder
+}
+
+// OrderBy 按Model的列排序，column可来自用户输入，需为字母或下划线开头的简单列名且是Model的列，否则生成sql返回错误；
+// 设置后替代Model中sort列的排序，多次调用按调用顺序排序
+func (builder *SqlBuilder) OrderBy(column string, desc bool) *SqlBuilder {
+	if !identifierPattern.MatchString(column) {
+		builder.err = buildErr("select", ErrInvalidIdentifier, column)
+		return builder
+	}
+	if t := builder.modelType(); nil == t || nil == getModelMeta(t).columns[column] {
+		builder.err = buildErr("select", ErrUnknownColumn, column)
+		return builder
+	}
+	builder.orderBy = append(builder.orderBy, sortColumn{column: column, desc: desc})
+	return builder
+}
+
+// Sort 按排序表达式排序，如 "-created_at,name"，-为降序，+或无前缀为升序，列校验同OrderBy
+func (builder *SqlBuilder) Sort(spec string) *SqlBuilder {
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if "" == item {
+			continue
+		}
+		switch item[0] {
+		case '-':
+			builder.OrderBy(item[1:], true)
+		case '+':
+			builder.OrderBy(item[1:], false)
+		default:
+			builder.OrderBy(item, false)
+		}
+	}
+	return builder
+}
+
+// Pick 只查询Model中指定的列，columns可来自用户输入，校验同OrderBy
+func (builder *SqlBuilder) Pick(columns ...string) *SqlBuilder {
+	t := builder.modelType()
+	for _, c := range columns {
+		if !identifierPattern.MatchString(c) {
+			builder.err = buildErr("select", ErrInvalidIdentifier, c)
+			return builder
+		}
+		if nil == t || nil == getModelMeta(t).columns[c] {
+			builder.err = buildErr("select", ErrUnknownColumn, c)
+			return builder
+		}
+	}
+	builder.pick = columns
+	return builder
+}
+
+// validate 校验表名、别名、列名等标识符，防止通过标识符注入
+func (builder *SqlBuilder) validate() error {
+	if nil != builder.err {
+		return builder.err
+	}
+	names := []string{builder.Table}
+	if "" != builder.alias {
+		names = append(names, builder.alias)
+	}
+	for _, j := range builder.joins {
+		names = append(names, j.table)
+		if "" != j.alias {
+			names = append(names, j.alias)
+		}
+	}
+	names = append(names, builder.returning...)
+	names = append(names, builder.groupBy...)
+	if nil != builder.changes {
+		names = append(names, builder.changes.columns...)
+		if t := builder.modelType(); nil != t {
+			for _, c := range builder.changes.columns {
+				if nil == getModelMeta(t).columns[c] {
+					return buildErr("update", ErrUnknownColumn, c)
+				}
+			}
+		}
+	}
+	for _, c := range builder.columns {
+		switch v := c.(type) {
+		case string:
+			names = append(names, v)
+		case Aggregate:
+			names = append(names, v.column)
+			if "" != v.alias {
+				names = append(names, v.alias)
+			}
+		}
+	}
+	for _, name := range names {
+		if !validIdentifier(name) {
+			return buildErr("", ErrInvalidIdentifier, name)
+		}
+	}
+	if t := builder.modelType(); nil != t && len(getModelMeta(t).invalid) > 0 {
+		return buildErr("", ErrInvalidIdentifier, getModelMeta(t).invalid[0])
+	}
+	return nil
+}
+
+// subquery 以外层的方言生成带括号的子查询，占位符在外层统一替换
+func (builder *SqlBuilder) subquery(d Dialect) (string, []interface{}, error) {
+	query := *builder
+	query.dialect = d
+	sql, params, err := query.buildSelect()
+	if nil != err {
+		return "", nil, err
+	}
+	return "(" + sql + ")", params, nil
+}
+
+// quoteColumn 以表别名限定并引用Model的列
+func (builder *SqlBuilder) quoteColumn(column string) string {
+	if "" != builder.alias {
+		return builder.dialect.Quote(builder.alias + "." + column)
+	}
+	return builder.dialect.Quote(column)
+}
+
+// generateFrom FROM及JOIN部分
+func (builder *SqlBuilder) generateFrom() (string, []interface{}, error) {
+	from := builder.dialect.Quote(builder.Table)
+	if "" != builder.alias {
+		from += " " + builder.dialect.Quote(builder.alias)
+	}
+	params := make([]interface{}, 0)
+	for _, j := range builder.joins {
+		from += j.kind + builder.dialect.Quote(j.table)
+		if "" != j.alias {
+			from += " " + builder.dialect.Quote(j.alias)
+		}
+		if nil == j.on {
+			continue
+		}
+		on, param, err := j.on.build(builder.dialect)
+		if nil != err {
+			return "", nil, err
+		}
+		if "" != on {
+			from += " ON " + on
+			params = append(params, param...)
+		}
+	}
+	return from, params, nil
+}
+
+// generateColumns Columns设置的查询列
+func (builder *SqlBuilder) generateColumns() string {
+	list := make([]string, 0, len(builder.columns))
+	for _, c := range builder.columns {
+		switch v := c.(type) {
+		case string:
+			list = append(list, builder.dialect.Quote(v))
+		case Aggregate:
+			if "" != v.alias {
+				list = append(list, v.expression(builder.dialect)+" AS "+builder.dialect.Quote(v.alias))
+			} else {
+				list = append(list, v.expression(builder.dialect))
+			}
+		}
+	}
+	return strings.Join(list, ",")
+}
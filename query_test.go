@@ -0,0 +1,91 @@
+package pocket
+
+import (
+	"reflect"
+	"testing"
+)
+
+type queryUser struct {
+	ID     *int64  `db:"id,pk"`
+	Name   *string `db:"name"`
+	Status *int    `db:"status"`
+}
+
+type queryOrder struct {
+	ID     *int64 `db:"id,pk"`
+	UserID *int64 `db:"user_id"`
+	Amount *int64 `db:"amount"`
+}
+
+func TestQueryJoinGroup(t *testing.T) {
+	cases := []struct {
+		dialect Dialect
+		want    string
+	}{
+		{MySQL, "SELECT `u`.`id`,COUNT(`o`.`id`) AS `orders`,SUM(`o`.`amount`) AS `total` FROM `user` `u` " +
+			"LEFT JOIN `order` `o` ON `o`.`user_id`=`u`.`id` AND `o`.`amount`>? " +
+			"WHERE `u`.`status`=? GROUP BY `u`.`id` HAVING COUNT(`o`.`id`)>? ORDER BY `u`.`id` DESC"},
+		{PostgreSQL, `SELECT "u"."id",COUNT("o"."id") AS "orders",SUM("o"."amount") AS "total" FROM "user" "u" ` +
+			`LEFT JOIN "order" "o" ON "o"."user_id"="u"."id" AND "o"."amount">$1 ` +
+			`WHERE "u"."status"=$2 GROUP BY "u"."id" HAVING COUNT("o"."id")>$3 ORDER BY "u"."id" DESC`},
+		{SQLite, `SELECT "u"."id",COUNT("o"."id") AS "orders",SUM("o"."amount") AS "total" FROM "user" "u" ` +
+			`LEFT JOIN "order" "o" ON "o"."user_id"="u"."id" AND "o"."amount">? ` +
+			`WHERE "u"."status"=? GROUP BY "u"."id" HAVING COUNT("o"."id")>? ORDER BY "u"."id" DESC`},
+	}
+	for _, c := range cases {
+		sql, params, err := NewSqlBuilder("user", &queryUser{}, c.dialect).As("u").
+			Columns("u.id", Count("o.id").As("orders"), Sum("o.amount").As("total")).
+			LeftJoin("order", "o", And(EqCol("o.user_id", "u.id"), Gt("o.amount", 0))).
+			Condition(Eq("u.status", 1)).
+			GroupBy("u.id").
+			Having(Count("o.id").Gt(2)).
+			OrderBy("id", true).
+			BuildSelect()
+		if nil != err || sql != c.want || !reflect.DeepEqual(params, []interface{}{0, 1, 2}) {
+			t.Errorf("%s join = %s, %v, %v, want %s", c.dialect, sql, params, err, c.want)
+		}
+	}
+}
+
+func TestQuerySubquery(t *testing.T) {
+	cases := []struct {
+		dialect Dialect
+		in      string
+		exists  string
+	}{
+		{
+			MySQL,
+			"SELECT `id`,`name`,`status` FROM `user` WHERE `status`=? AND `id` IN (SELECT `user_id` FROM `order` WHERE `amount`>? AND `status`=?) AND `name`<>?",
+			"SELECT `u`.`id`,`u`.`name`,`u`.`status` FROM `user` `u` WHERE `u`.`status`=? AND NOT EXISTS (SELECT `o`.`id` FROM `order` `o` WHERE `o`.`user_id`=`u`.`id` AND `o`.`amount`>?) AND `u`.`name`=?",
+		},
+		{
+			PostgreSQL,
+			`SELECT "id","name","status" FROM "user" WHERE "status"=$1 AND "id" IN (SELECT "user_id" FROM "order" WHERE "amount">$2 AND "status"=$3) AND "name"<>$4`,
+			`SELECT "u"."id","u"."name","u"."status" FROM "user" "u" WHERE "u"."status"=$1 AND NOT EXISTS (SELECT "o"."id" FROM "order" "o" WHERE "o"."user_id"="u"."id" AND "o"."amount">$2) AND "u"."name"=$3`,
+		},
+		{
+			SQLite,
+			`SELECT "id","name","status" FROM "user" WHERE "status"=? AND "id" IN (SELECT "user_id" FROM "order" WHERE "amount">? AND "status"=?) AND "name"<>?`,
+			`SELECT "u"."id","u"."name","u"."status" FROM "user" "u" WHERE "u"."status"=? AND NOT EXISTS (SELECT "o"."id" FROM "order" "o" WHERE "o"."user_id"="u"."id" AND "o"."amount">?) AND "u"."name"=?`,
+		},
+	}
+	for _, c := range cases {
+		// 子查询未指定方言，按外层的方言生成
+		paid := NewSqlBuilder("order", &queryOrder{}).Pick("user_id").Condition(And(Gt("amount", 100), Eq("status", 2)))
+		sql, params, err := NewSqlBuilder("user", &queryUser{}, c.dialect).
+			Condition(And(Eq("status", 1), In("id", paid), Ne("name", "x"))).
+			BuildSelect()
+		if nil != err || sql != c.in || !reflect.DeepEqual(params, []interface{}{1, 100, 2, "x"}) {
+			t.Errorf("%s in subquery = %s, %v, %v, want %s", c.dialect, sql, params, err, c.in)
+		}
+
+		orders := NewSqlBuilder("order", &queryOrder{}).As("o").Pick("id").
+			Condition(And(EqCol("o.user_id", "u.id"), Gt("o.amount", 5)))
+		sql, params, err = NewSqlBuilder("user", &queryUser{}, c.dialect).As("u").
+			Condition(And(Eq("u.status", 1), NotExists(orders), Eq("u.name", "y"))).
+			BuildSelect()
+		if nil != err || sql != c.exists || !reflect.DeepEqual(params, []interface{}{1, 5, "y"}) {
+			t.Errorf("%s exists = %s, %v, %v, want %s", c.dialect, sql, params, err, c.exists)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Package pocket Create at 2026-10-18 16:10
+package pocket
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sensitive 敏感参数，执行时按原值传给驱动，日志中显示为***；
+// Model及条件结构体中tag带sensitive的字段自动包装，如 `db:"password,set,sensitive"`
+type Sensitive struct {
+	V interface{}
+}
+
+// Value 实现driver.Valuer，返回原值
+func (s Sensitive) Value() (driver.Value, error) {
+	return driver.DefaultParameterConverter.ConvertValue(s.V)
+}
+
+func (s Sensitive) String() string {
+	return "***"
+}
+
+// param 字段对应的sql参数，带sensitive时包装为Sensitive
+func (f *fieldMeta) param(v interface{}) interface{} {
+	if f.has("sensitive") {
+		return Sensitive{V: v}
+	}
+	return v
+}
+
+// Interpolate 将参数代入sql，仅用于日志显示，不可用于执行；支持?及$n占位符，Sensitive参数显示为'***'
+func Interpolate(query string, params []interface{}) string {
+	var b strings.Builder
+	var quote byte
+	n := 0
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case 0 != quote:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '?' && n < len(params):
+			b.WriteString(displayValue(params[n]))
+			n++
+			continue
+		case c == '$' && i+1 < len(query) && query[i+1] >= '0' && query[i+1] <= '9':
+			j := i + 1
+			for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+				j++
+			}
+			if k, err := strconv.Atoi(query[i+1 : j]); nil == err && k >= 1 && k <= len(params) {
+				b.WriteString(displayValue(params[k-1]))
+				i = j - 1
+				continue
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// displayValue 参数的显示形式
+func displayValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "NULL"
+	case Sensitive:
+		return "'***'"
+	case string:
+		return "'" + strings.Replace(val, "'", "''", -1) + "'"
+	case []byte:
+		return "'" + strings.Replace(string(val), "'", "''", -1) + "'"
+	case time.Time:
+		return "'" + val.Format("2006-01-02 15:04:05.999999") + "'"
+	case bool:
+		if val {
+			return "TRUE"
+		}
+		return "FALSE"
+	case driver.Valuer:
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return "NULL"
+		}
+		dv, err := val.Value()
+		if nil != err {
+			return "?"
+		}
+		return displayValue(dv)
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return "NULL"
+		}
+		return displayValue(rv.Elem().Interface())
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// queryLog 记录语句，出错时为ERROR，超过慢查询阈值时为WARN，否则为INFO；rows<0时不记录影响行数，
+// 日志中的位置为调用Executor的位置
+func (e *Executor) queryLog(start time.Time, query string, params []interface{}, rows int64, err error) {
+	if nil == e.logger {
+		if nil != err {
+			DefaultLogger.Error(err.Error())
+		}
+		return
+	}
+	elapsed := time.Since(start)
+	file, line := externalCaller()
+	msg := fmt.Sprintf("[%s]", elapsed)
+	if rows >= 0 {
+		msg += fmt.Sprintf(" rows:%d", rows)
+	}
+	msg += " " + Interpolate(query, params)
+	switch {
+	case nil != err:
+		e.logger.logAt(ErrorLevel, file, line, msg, "|", err.Error())
+	case e.slow > 0 && elapsed >= e.slow:
+		e.logger.logAt(WarnLevel, file, line, "slow query", msg)
+	default:
+		e.logger.logAt(InfoLevel, file, line, msg)
+	}
+}
+
+// externalCaller 调用栈中第一个不在本包内的位置，即调用Executor的位置，格式与getCaller相同
+func externalCaller() (string, int) {
+	pc := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pc[:runtime.Callers(2, pc)])
+	prefix := reflect.TypeOf(Executor{}).PkgPath() + "."
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, prefix) {
+			file := frame.File
+			if i := strings.LastIndex(file, "/"); i > 0 {
+				if j := strings.LastIndex(file[:i], "/"); j >= 0 {
+					file = file[j+1:]
+				}
+			}
+			return file, frame.Line
+		}
+		if !more {
+			return "", 0
+		}
+	}
+}
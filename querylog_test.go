@@ -0,0 +1,67 @@
+package pocket_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/nekobox69/pocket"
+)
+
+// stubDB 只实现ExecContext的DBTX
+type stubDB struct {
+	err error
+}
+
+func (s stubDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if nil != s.err {
+		return nil, s.err
+	}
+	return stubResult{}, nil
+}
+
+func (s stubDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s stubDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+type stubResult struct{}
+
+func (stubResult) LastInsertId() (int64, error) { return 1, nil }
+func (stubResult) RowsAffected() (int64, error) { return 1, nil }
+
+func TestQueryLogCaller(t *testing.T) {
+	var buf bytes.Buffer
+	// 复制的Logback与DefaultLogger共用log.Logger，结束后恢复输出
+	logger := *pocket.DefaultLogger
+	logger.SetLevel(pocket.InfoLevel)
+	logger.SetOutput(&buf)
+	defer logger.SetOutput(os.Stdout)
+
+	ctx := context.Background()
+	pocket.NewExecutor(stubDB{}).Logger(&logger).Exec(ctx, "UPDATE t SET a=? WHERE id=?", pocket.Sensitive{V: "x"}, 1)
+	pocket.NewExecutor(stubDB{err: errors.New("boom")}).Logger(&logger).Exec(ctx, "DELETE FROM t")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("log lines = %q", buf.String())
+	}
+	for _, line := range lines {
+		if strings.Count(line, ".go:") != 1 || !strings.Contains(line, "querylog_test.go:") {
+			t.Errorf("log line should carry only the caller location: %s", line)
+		}
+	}
+	if !strings.Contains(lines[0], "| INFO |") || !strings.Contains(lines[0], "rows:1 UPDATE t SET a='***' WHERE id=1") {
+		t.Errorf("info line = %s", lines[0])
+	}
+	if !strings.Contains(lines[1], "| ERROR |") || !strings.Contains(lines[1], "boom") {
+		t.Errorf("error line = %s", lines[1])
+	}
+}
@@ -0,0 +1,202 @@
+// Package pocket Create at 2026-10-18 17:30
+package pocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	excelize "github.com/360EntSecGroup-Skylar/excelize/v2"
+)
+
+// annotateStyle 标注错误单元格的样式
+const annotateStyle = `{"fill":{"type":"pattern","color":["#FFC7CE"],"pattern":1}}`
+
+// ImportIssue 导入时的单元格错误，缺少列时Column为空
+type ImportIssue struct {
+	Sheet  string `json:"sheet"`
+	Row    int    `json:"row"`    // Excel中的行号，表头为第1行
+	Column string `json:"column"` // 列名，如 A、AA
+	Header string `json:"header"` // 表头
+	Value  string `json:"value"`  // 单元格的原始值
+	Reason string `json:"reason"`
+}
+
+func (i ImportIssue) String() string {
+	if "" == i.Column {
+		return fmt.Sprintf("%s 第%d行 %s: %s", i.Sheet, i.Row, i.Header, i.Reason)
+	}
+	return fmt.Sprintf("%s %s%d %s %q: %s", i.Sheet, i.Column, i.Row, i.Header, i.Value, i.Reason)
+}
+
+// ImportReport 导入的错误报告，有错误的行不会导入；实现error，可用errors.As取出
+type ImportReport struct {
+	Issues []ImportIssue `json:"issues"`
+}
+
+func (r *ImportReport) Error() string {
+	if len(r.Issues) == 1 {
+		return "导入数据有误: " + r.Issues[0].String()
+	}
+	return fmt.Sprintf("导入数据有误，共%d处: %s 等", len(r.Issues), r.Issues[0].String())
+}
+
+// add 记录错误
+func (r *ImportReport) add(issue ImportIssue) {
+	r.Issues = append(r.Issues, issue)
+}
+
+// Annotate 在导入时的原文件中标注错误后写入w：错误单元格标红并添加批注，缺少的列在表头第一格添加批注
+func (r *ImportReport) Annotate(original io.Reader, w io.Writer) error {
+	xlsx, err := excelize.OpenReader(original)
+	if nil != err {
+		DefaultLogger.Error(err.Error())
+		return err
+	}
+	style, err := xlsx.NewStyle(annotateStyle)
+	if nil != err {
+		DefaultLogger.Error(err.Error())
+		return err
+	}
+	// 同一单元格的多个错误合并为一条批注
+	cells := make([]string, 0)
+	reasons := make(map[string][]string)
+	for _, i := range r.Issues {
+		cell := i.Column + strconv.Itoa(i.Row)
+		reason := i.Reason
+		if "" == i.Column {
+			cell = "A1"
+			reason = i.Header + ": " + i.Reason
+		}
+		key := i.Sheet + "!" + cell
+		if _, ok := reasons[key]; !ok {
+			cells = append(cells, key)
+		}
+		reasons[key] = append(reasons[key], reason)
+	}
+	for _, key := range cells {
+		i := strings.LastIndex(key, "!")
+		sheet, cell := key[:i], key[i+1:]
+		if err = xlsx.SetCellStyle(sheet, cell, cell, style); nil != err {
+			DefaultLogger.Error(err.Error())
+			return err
+		}
+		comment, _ := json.Marshal(map[string]string{"author": "pocket: ", "text": strings.Join(reasons[key], "\n")})
+		if err = xlsx.AddComment(sheet, cell, string(comment)); nil != err {
+			DefaultLogger.Error(err.Error())
+			return err
+		}
+	}
+	if err = xlsx.Write(w); nil != err {
+		DefaultLogger.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// validateRule 导入校验规则，tag格式 `validate:"required,min=1,max=10,len=11,oneof=a b c"`，
+// min/max对数字比较数值，对字符串比较字符数；除required外，单元格为空时不校验
+type validateRule struct {
+	name  string
+	param string
+}
+
+// parseValidate 解析validate tag
+func parseValidate(tag string) ([]validateRule, error) {
+	rules := make([]validateRule, 0)
+	for _, item := range strings.Split(tag, ",") {
+		item = strings.TrimSpace(item)
+		if "" == item {
+			continue
+		}
+		rule := validateRule{name: item}
+		if i := strings.Index(item, "="); i >= 0 {
+			rule.name, rule.param = item[:i], item[i+1:]
+		}
+		switch rule.name {
+		case "required":
+		case "min", "max", "len":
+			if _, err := strconv.ParseFloat(rule.param, 64); nil != err {
+				return nil, fmt.Errorf("validate: %s需为数字", rule.name)
+			}
+		case "oneof":
+			if "" == rule.param {
+				return nil, fmt.Errorf("validate: oneof缺少可选值")
+			}
+		default:
+			return nil, fmt.Errorf("validate: 不支持的规则 %s", rule.name)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// required 是否为必填
+func required(rules []validateRule) bool {
+	for _, r := range rules {
+		if "required" == r.name {
+			return true
+		}
+	}
+	return false
+}
+
+// validate 校验原始值raw及转换后的字段v，返回第一个不满足的规则的原因
+func validate(rules []validateRule, raw string, v reflect.Value) string {
+	if "" == raw {
+		if required(rules) {
+			return "必填"
+		}
+		return ""
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	for _, r := range rules {
+		limit, _ := strconv.ParseFloat(r.param, 64)
+		switch r.name {
+		case "min", "max":
+			n, unit := 0.0, ""
+			switch v.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				n = float64(v.Int())
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				n = float64(v.Uint())
+			case reflect.Float32, reflect.Float64:
+				n = v.Float()
+			default:
+				n, unit = float64(utf8.RuneCountInString(raw)), "长度"
+			}
+			if "min" == r.name && n < limit {
+				return fmt.Sprintf("%s不能小于%s", unit, r.param)
+			}
+			if "max" == r.name && n > limit {
+				return fmt.Sprintf("%s不能大于%s", unit, r.param)
+			}
+		case "len":
+			if float64(utf8.RuneCountInString(raw)) != limit {
+				return fmt.Sprintf("长度应为%s", r.param)
+			}
+		case "oneof":
+			value := fmt.Sprintf("%v", v.Interface())
+			ok := false
+			for _, item := range strings.Fields(r.param) {
+				if item == value || item == raw {
+					ok = true
+					break
+				}
+			}
+			if !ok {
+				return fmt.Sprintf("应为 %s 之一", strings.Join(strings.Fields(r.param), ","))
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,87 @@
+// Package pocket Create at 2026-10-18 11:05
+package pocket
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+const txErr = "不支持的事务类型，需为*sql.DB、*sql.Tx或*Tx"
+
+// Tx 事务，嵌套的WithTx通过SAVEPOINT实现
+type Tx struct {
+	*sql.Tx
+	savepoint *int
+}
+
+// WithTx 在事务中执行fn，fn返回nil时提交，返回错误或panic时回滚。
+// db为*sql.DB时开启新事务；为*Tx或*sql.Tx时创建SAVEPOINT，成功时RELEASE，失败时回滚到该SAVEPOINT
+func WithTx(ctx context.Context, db DBTX, fn func(tx *Tx) error) (err error) {
+	file, line := getCaller(2)
+	var tx *Tx
+	savepoint := ""
+	switch d := db.(type) {
+	case *Tx:
+		tx = d
+		if nil == tx.savepoint {
+			tx.savepoint = new(int)
+		}
+	case *sql.Tx:
+		tx = &Tx{Tx: d, savepoint: new(int)}
+	case *sql.DB:
+		t, err := d.BeginTx(ctx, nil)
+		if nil != err {
+			DefaultLogger.Error(err.Error())
+			return err
+		}
+		tx = &Tx{Tx: t, savepoint: new(int)}
+	default:
+		DefaultLogger.Error(txErr)
+		return errors.New(txErr)
+	}
+	if _, ok := db.(*sql.DB); !ok {
+		*tx.savepoint++
+		savepoint = fmt.Sprintf("sp_%d", *tx.savepoint)
+		if _, err = tx.ExecContext(ctx, "SAVEPOINT "+savepoint); nil != err {
+			DefaultLogger.Error(err.Error())
+			return err
+		}
+	}
+
+	defer func() {
+		if r := recover(); nil != r {
+			tx.rollback(ctx, savepoint, fmt.Errorf("panic: %v", r), file, line)
+			panic(r)
+		}
+		if nil != err {
+			tx.rollback(ctx, savepoint, err, file, line)
+			return
+		}
+		if "" == savepoint {
+			err = tx.Commit()
+		} else {
+			_, err = tx.ExecContext(ctx, "RELEASE SAVEPOINT "+savepoint)
+		}
+		if nil != err {
+			DefaultLogger.logAt(ErrorLevel, file, line, "commit failed:", err.Error())
+		}
+	}()
+	return fn(tx)
+}
+
+// rollback 回滚事务或回滚到savepoint，日志的位置为WithTx的调用处
+func (tx *Tx) rollback(ctx context.Context, savepoint string, cause error, file string, line int) {
+	var err error
+	if "" == savepoint {
+		err = tx.Rollback()
+	} else {
+		_, err = tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint)
+	}
+	if nil != err {
+		DefaultLogger.logAt(ErrorLevel, file, line, "rollback failed:", err.Error(), "cause:", cause.Error())
+		return
+	}
+	DefaultLogger.logAt(WarnLevel, file, line, "rollback:", cause.Error())
+}
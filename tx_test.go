@@ -0,0 +1,102 @@
+package pocket
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWithTxCommit(t *testing.T) {
+	queries := make([]string, 0)
+	db := openFake(t, func(query string, args []driver.Value) fakeResponse {
+		queries = append(queries, query)
+		return fakeResponse{affected: 1}
+	})
+	var buf bytes.Buffer
+	DefaultLogger.SetOutput(&buf)
+	defer DefaultLogger.SetOutput(os.Stdout)
+
+	err := WithTx(context.Background(), db, func(tx *Tx) error {
+		_, err := tx.Exec("UPDATE t SET a=1")
+		return err
+	})
+	if nil != err || !reflect.DeepEqual(queries, []string{"UPDATE t SET a=1", "COMMIT"}) {
+		t.Errorf("commit = %v, queries %v", err, queries)
+	}
+	if "" != buf.String() {
+		t.Errorf("commit should not log: %s", buf.String())
+	}
+}
+
+func TestWithTxRollback(t *testing.T) {
+	queries := make([]string, 0)
+	db := openFake(t, func(query string, args []driver.Value) fakeResponse {
+		queries = append(queries, query)
+		return fakeResponse{affected: 1}
+	})
+	var buf bytes.Buffer
+	DefaultLogger.SetOutput(&buf)
+	defer DefaultLogger.SetOutput(os.Stdout)
+	ctx := context.Background()
+
+	failed := errors.New("failed")
+	if err := WithTx(ctx, db, func(tx *Tx) error { return failed }); err != failed {
+		t.Errorf("rollback err = %v, want fn error", err)
+	}
+	func() {
+		defer func() {
+			if r := recover(); "boom" != r {
+				t.Errorf("recovered %v, want the original panic", r)
+			}
+		}()
+		WithTx(ctx, db, func(tx *Tx) error { panic("boom") })
+	}()
+	if !reflect.DeepEqual(queries, []string{"ROLLBACK", "ROLLBACK"}) {
+		t.Errorf("queries = %v, want 2 rollbacks", queries)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "rollback: failed") || !strings.Contains(lines[1], "rollback: panic: boom") {
+		t.Fatalf("log = %q", buf.String())
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, "| WARN |") || strings.Count(line, ".go:") != 1 || !strings.Contains(line, "tx_test.go:") {
+			t.Errorf("log line should carry only the WithTx caller: %s", line)
+		}
+	}
+}
+
+func TestWithTxSavepoint(t *testing.T) {
+	queries := make([]string, 0)
+	db := openFake(t, func(query string, args []driver.Value) fakeResponse {
+		queries = append(queries, query)
+		return fakeResponse{affected: 1}
+	})
+	DefaultLogger.SetOutput(&bytes.Buffer{})
+	defer DefaultLogger.SetOutput(os.Stdout)
+	ctx := context.Background()
+
+	failed := errors.New("failed")
+	err := WithTx(ctx, db, func(tx *Tx) error {
+		if err := WithTx(ctx, tx, func(inner *Tx) error { return nil }); nil != err {
+			return err
+		}
+		if err := WithTx(ctx, tx, func(inner *Tx) error { return failed }); err != failed {
+			t.Errorf("nested err = %v, want fn error", err)
+		}
+		return nil
+	})
+	want := []string{
+		"SAVEPOINT sp_1", "RELEASE SAVEPOINT sp_1",
+		"SAVEPOINT sp_2", "ROLLBACK TO SAVEPOINT sp_2",
+		"COMMIT",
+	}
+	if nil != err || !reflect.DeepEqual(queries, want) {
+		t.Errorf("nested = %v, queries %v, want %v", err, queries, want)
+	}
+}